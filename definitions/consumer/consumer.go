@@ -0,0 +1,354 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gkarlik/quark-go/metrics"
+	"github.com/streadway/amqp"
+)
+
+// Message is a message delivered to handler
+type Message struct {
+	Topic       string
+	ContentType string
+	Body        []byte
+	Headers     map[string]interface{}
+	// broker delivered message before, e.g. consumer was stopped before acknowledging it
+	Redelivered bool
+	// attempt of handling the message by this consumer, counted from 1
+	Attempt int
+}
+
+// Handler processes message. Message is acknowledged when handler returns no error,
+// otherwise handling is retried. Handler has to be idempotent, as message can be delivered more than once.
+type Handler func(ctx context.Context, msg Message) error
+
+// permanentError marks failure which is not worth retrying
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Permanent marks handler error as permanent, so message is moved to dead-letter topic without retrying
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// RetryPolicy configures retries of failed messages with exponential backoff and full jitter
+type RetryPolicy struct {
+	// total number of attempts including the first one
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Backoff returns randomized delay before given retry (counted from 1)
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	d := p.BaseDelay << uint(retry-1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// Metrics counts handled messages, counters which are not set are not reported
+type Metrics struct {
+	// messages handled successfully
+	Processed metrics.Counter
+	// failed attempts of handling messages
+	Failed metrics.Counter
+	// messages delivered again by broker
+	Redelivered metrics.Counter
+	// messages moved to dead-letter topic
+	DeadLettered metrics.Counter
+}
+
+// Consumer consumes messages of the topic from RabbitMQ by pool of workers, acknowledging
+// each message explicitly once it is handled. Messages failing all attempts are moved to dead-letter topic.
+//
+// Messages are routed by topic exchange to queue of each subscriber. Consumers of the same group
+// share durable queue and compete for its messages, consumer without group is given its own
+// temporary queue, which receives messages published while the consumer is running.
+type Consumer struct {
+	url             string
+	exchange        string
+	topic           string
+	group           string
+	deadLetterTopic string
+	handler         Handler
+
+	workers  int
+	prefetch int
+	retry    RetryPolicy
+	metrics  Metrics
+
+	// publishing channel in confirm mode, shared by workers
+	mu       sync.Mutex
+	pub      *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+// Option configures Consumer
+type Option func(*Consumer)
+
+// DeadLetterTopic sets topic of messages failing all attempts, by default topic name with ".dead" suffix
+func DeadLetterTopic(topic string) Option {
+	return func(c *Consumer) {
+		c.deadLetterTopic = topic
+	}
+}
+
+// Group sets name of consumer group, e.g. name of the service, sharing messages of the topic
+func Group(name string) Option {
+	return func(c *Consumer) {
+		c.group = name
+	}
+}
+
+// Workers sets number of messages handled concurrently
+func Workers(n int) Option {
+	return func(c *Consumer) {
+		c.workers = n
+	}
+}
+
+// Prefetch sets number of unacknowledged messages broker delivers to consumer ahead
+func Prefetch(n int) Option {
+	return func(c *Consumer) {
+		c.prefetch = n
+	}
+}
+
+// Retry sets retry policy
+func Retry(p RetryPolicy) Option {
+	return func(c *Consumer) {
+		c.retry = p
+	}
+}
+
+// WithMetrics sets counters of handled messages
+func WithMetrics(m Metrics) Option {
+	return func(c *Consumer) {
+		c.metrics = m
+	}
+}
+
+// New creates consumer of the topic published to the exchange, handled by handler. Broker is given by AMQP URL.
+func New(url, exchange, topic string, handler Handler, opts ...Option) *Consumer {
+	c := &Consumer{
+		url:             url,
+		exchange:        exchange,
+		topic:           topic,
+		deadLetterTopic: topic + ".dead",
+		handler:         handler,
+		workers:         1,
+		prefetch:        1,
+		retry:           RetryPolicy{MaxAttempts: 1},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run consumes messages until context is done or broker connection is lost. Messages being handled
+// are finished before Run returns, messages prefetched but not handled yet are returned to the broker.
+func (c *Consumer) Run(ctx context.Context) error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	// channel may be closed by the broker alone, e.g. on precondition failure
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	if err := ch.Qos(c.prefetch, 0, false); err != nil {
+		return err
+	}
+	// exchange is declared the same way as by publishers
+	if err := ch.ExchangeDeclare(c.exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return err
+	}
+	queue, err := c.declareQueue(ch)
+	if err != nil {
+		return err
+	}
+	if err := ch.QueueBind(queue, c.topic, c.exchange, false, nil); err != nil {
+		return err
+	}
+	// dead messages are kept until someone looks at them
+	if _, err := ch.QueueDeclare(c.deadLetterTopic, true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	pub, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err := pub.Confirm(false); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.pub, c.confirms = pub, pub.NotifyPublish(make(chan amqp.Confirmation, 1))
+	c.mu.Unlock()
+
+	deliveries, err := ch.Consume(queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(c.workers)
+	for n := 0; n < c.workers; n++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d, ok := <-deliveries:
+					if !ok {
+						return
+					}
+					c.handle(ctx, d)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	// deliveries are closed together with the channel, whether the connection is still open or not
+	select {
+	case err := <-closed:
+		if err != nil {
+			return err
+		}
+	case err := <-chClosed:
+		if err != nil {
+			return err
+		}
+	}
+	return errors.New("Broker connection closed")
+}
+
+// helper function to declare queue of the consumer, returns its name. Queue of the group outlives
+// consumers, so messages published meanwhile are not lost.
+func (c *Consumer) declareQueue(ch *amqp.Channel) (string, error) {
+	if c.group != "" {
+		q, err := ch.QueueDeclare(c.group+"."+c.topic, true, false, false, false, nil)
+		return q.Name, err
+	}
+	// queue named by the broker is removed together with the connection
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	return q.Name, err
+}
+
+// function to handle single delivery with retries. Lost acknowledgement only means the message
+// is delivered again, so acknowledgement errors are ignored.
+func (c *Consumer) handle(ctx context.Context, d amqp.Delivery) {
+	if d.Redelivered {
+		inc(c.metrics.Redelivered)
+	}
+
+	msg := Message{
+		Topic:       c.topic,
+		ContentType: d.ContentType,
+		Body:        d.Body,
+		Headers:     d.Headers,
+		Redelivered: d.Redelivered,
+	}
+
+	var err error
+	for msg.Attempt = 1; ; msg.Attempt++ {
+		// message is handled to the end even when consumer is stopped meanwhile
+		if err = c.handler(context.Background(), msg); err == nil {
+			inc(c.metrics.Processed)
+
+			d.Ack(false)
+			return
+		}
+		inc(c.metrics.Failed)
+
+		if _, ok := err.(*permanentError); ok || msg.Attempt >= c.retry.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			// message is returned to the broker and retried by another consumer
+			d.Nack(false, true)
+			return
+		case <-time.After(c.retry.Backoff(msg.Attempt)):
+		}
+	}
+
+	// message is acknowledged only when it is safely stored in dead-letter topic
+	if err := c.deadLetter(d, msg.Attempt, err); err != nil {
+		d.Nack(false, true)
+		return
+	}
+	inc(c.metrics.DeadLettered)
+
+	d.Ack(false)
+}
+
+// helper function to publish failed message to dead-letter topic together with reason of failure
+func (c *Consumer) deadLetter(d amqp.Delivery, attempts int, reason error) error {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers["x-original-topic"] = c.topic
+	headers["x-attempts"] = int32(attempts)
+	headers["x-error"] = reason.Error()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.pub.Publish("", c.deadLetterTopic, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    time.Now(),
+		Body:         d.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	confirm, ok := <-c.confirms
+	if !ok {
+		return errors.New("Broker connection closed")
+	}
+	if !confirm.Ack {
+		return fmt.Errorf("Message not accepted by %s topic", c.deadLetterTopic)
+	}
+	return nil
+}
+
+func inc(c metrics.Counter) {
+	if c != nil {
+		c.Inc()
+	}
+}
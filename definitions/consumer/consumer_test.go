@@ -0,0 +1,46 @@
+package consumer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for retry := 1; retry <= 10; retry++ {
+		max := p.BaseDelay << uint(retry-1)
+		if max > p.MaxDelay {
+			max = p.MaxDelay
+		}
+		for i := 0; i < 100; i++ {
+			if d := p.Backoff(retry); d < 0 || d >= max {
+				t.Fatalf("Retry %d: expected delay in [0, %s), got %s", retry, max, d)
+			}
+		}
+	}
+}
+
+func TestBackoffOverflow(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	// shifted delay overflows to non-positive value, which is capped as well
+	if d := p.Backoff(64); d < 0 || d >= p.MaxDelay {
+		t.Errorf("Expected delay in [0, %s), got %s", p.MaxDelay, d)
+	}
+	if d := (RetryPolicy{}).Backoff(1); d != 0 {
+		t.Errorf("Expected no delay without policy, got %s", d)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	err := Permanent(errors.New("invalid payload"))
+
+	if _, ok := err.(*permanentError); !ok {
+		t.Errorf("Expected permanent error, got %T", err)
+	}
+	if err.Error() != "invalid payload" {
+		t.Errorf("Expected wrapped message, got '%s'", err)
+	}
+}
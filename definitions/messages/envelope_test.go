@@ -0,0 +1,19 @@
+package messages
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []string{
+		`not json`,
+		`{"type":"Versioned","schema_version":1,"payload":{}}`,
+		`{"id":"1","schema_version":1,"payload":{}}`,
+		`{"id":"1","type":"Versioned","payload":{}}`,
+		`{"id":"1","type":"Versioned","schema_version":1}`,
+	}
+
+	for _, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected error for envelope %s", data)
+		}
+	}
+}
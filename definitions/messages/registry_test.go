@@ -0,0 +1,128 @@
+package messages
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// message of schema version 3, still able to read version 2
+type versioned struct {
+	Value int `json:"value"`
+}
+
+func (m *versioned) MessageType() string { return "Versioned" }
+
+func (m *versioned) SchemaVersion() int { return 3 }
+
+// value receiver variant of the message, which cannot be decoded into
+type versionedValue struct{}
+
+func (versionedValue) MessageType() string { return "VersionedValue" }
+
+func (versionedValue) SchemaVersion() int { return 1 }
+
+func newTestRegistry(t *testing.T) *Registry {
+	r := NewRegistry()
+	if err := r.Register(&versioned{}, JSON, 2); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		m          Message
+		encoding   string
+		minVersion int
+	}{
+		{"not a pointer", versionedValue{}, JSON, 1},
+		{"not a protobuf message", &versioned{}, Protobuf, 1},
+		{"unknown encoding", &versioned{}, "xml", 1},
+		{"zero minimal version", &versioned{}, JSON, 0},
+		{"minimal version above current", &versioned{}, JSON, 4},
+		{"duplicated type", &versioned{}, JSON, 1},
+	}
+
+	r := newTestRegistry(t)
+	for _, tt := range tests {
+		if err := r.Register(tt.m, tt.encoding, tt.minVersion); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestWrapAndOpen(t *testing.T) {
+	r := newTestRegistry(t)
+
+	e, err := r.Wrap(&versioned{Value: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID == "" || e.Type != "Versioned" || e.SchemaVersion != 3 || e.Encoding != JSON {
+		t.Errorf("Unexpected envelope %+v", e)
+	}
+
+	data, err := e.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, m, err := r.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m.(*versioned); !ok || v.Value != 42 {
+		t.Errorf("Expected message with value 42, got %#v", m)
+	}
+
+	if _, err := r.Wrap(&UserDeleted{}); err == nil {
+		t.Error("Expected unregistered message to be rejected")
+	}
+}
+
+func TestOpenChecksVersion(t *testing.T) {
+	tests := []struct {
+		version int
+		ok      bool
+	}{
+		{1, false},
+		{2, true},
+		{3, true},
+		{4, false},
+	}
+
+	r := newTestRegistry(t)
+	for _, tt := range tests {
+		e := &Envelope{ID: "1", Type: "Versioned", SchemaVersion: tt.version, Encoding: JSON, Payload: json.RawMessage(`{"value":1}`)}
+
+		_, err := r.Open(e)
+		if tt.ok && err != nil {
+			t.Errorf("Version %d: unexpected error %v", tt.version, err)
+		}
+		if !tt.ok {
+			if ve, ok := err.(*IncompatibleVersionError); !ok || ve.MinVersion != 2 || ve.MaxVersion != 3 {
+				t.Errorf("Version %d: expected incompatible version error, got %v", tt.version, err)
+			}
+		}
+	}
+}
+
+func TestOpenRejectsInvalidMessages(t *testing.T) {
+	r := newTestRegistry(t)
+
+	if _, err := r.Open(&Envelope{Type: "Unknown", SchemaVersion: 1}); err == nil {
+		t.Error("Expected unknown type error")
+	} else if _, ok := err.(*UnknownTypeError); !ok {
+		t.Errorf("Expected unknown type error, got %v", err)
+	}
+
+	e := &Envelope{Type: "Versioned", SchemaVersion: 3, Encoding: Protobuf, Payload: json.RawMessage(`{}`)}
+	if _, err := r.Open(e); err == nil {
+		t.Error("Expected encoding mismatch error")
+	}
+
+	e = &Envelope{Type: "Versioned", SchemaVersion: 3, Encoding: JSON, Payload: json.RawMessage(`"text"`)}
+	if _, err := r.Open(e); err == nil {
+		t.Error("Expected invalid payload error")
+	}
+}
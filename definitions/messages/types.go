@@ -0,0 +1,82 @@
+package messages
+
+import "time"
+
+// message types exchanged by services
+const (
+	CalculationPerformedType = "CalculationPerformed"
+	UserRegisteredType       = "UserRegistered"
+	PasswordChangedType      = "PasswordChanged"
+	UserDeletedType          = "UserDeleted"
+)
+
+// topic exchange messages are published to, routed by their topics
+const Exchange = "events"
+
+// topics of messages
+const (
+	CalculationsTopic = "Calculations"
+	UsersTopic        = "Users"
+)
+
+// Registered knows all messages exchanged by services
+var Registered = NewRegistry()
+
+func init() {
+	Registered.MustRegister(&CalculationPerformed{}, JSON, 1)
+	Registered.MustRegister(&UserRegistered{}, JSON, 1)
+	Registered.MustRegister(&PasswordChanged{}, JSON, 1)
+	Registered.MustRegister(&UserDeleted{}, JSON, 1)
+}
+
+// CalculationPerformed is published by SumService and MultiplyService after each calculation
+type CalculationPerformed struct {
+	Operation string        `json:"operation"`
+	Operands  []int64       `json:"operands"`
+	Result    int64         `json:"result"`
+	User      string        `json:"user,omitempty"`
+	Latency   time.Duration `json:"latency_ns"`
+	TraceID   string        `json:"trace_id,omitempty"`
+}
+
+// MessageType returns name identifying type of the message
+func (m *CalculationPerformed) MessageType() string { return CalculationPerformedType }
+
+// SchemaVersion returns version of the message structure
+func (m *CalculationPerformed) SchemaVersion() int { return 1 }
+
+// UserRegistered is published by the gateway when user registers account
+type UserRegistered struct {
+	UserID uint   `json:"user_id"`
+	Login  string `json:"login"`
+}
+
+// MessageType returns name identifying type of the message
+func (m *UserRegistered) MessageType() string { return UserRegisteredType }
+
+// SchemaVersion returns version of the message structure
+func (m *UserRegistered) SchemaVersion() int { return 1 }
+
+// PasswordChanged is published by the gateway when user changes password
+type PasswordChanged struct {
+	UserID uint   `json:"user_id"`
+	Login  string `json:"login"`
+}
+
+// MessageType returns name identifying type of the message
+func (m *PasswordChanged) MessageType() string { return PasswordChangedType }
+
+// SchemaVersion returns version of the message structure
+func (m *PasswordChanged) SchemaVersion() int { return 1 }
+
+// UserDeleted is published by the gateway when user deletes account
+type UserDeleted struct {
+	UserID uint   `json:"user_id"`
+	Login  string `json:"login"`
+}
+
+// MessageType returns name identifying type of the message
+func (m *UserDeleted) MessageType() string { return UserDeletedType }
+
+// SchemaVersion returns version of the message structure
+func (m *UserDeleted) SchemaVersion() int { return 1 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	auth "github.com/gkarlik/quark-go/middleware/auth/jwt"
+)
+
+// names of claims properties holding user roles and permissions
+const (
+	rolesProperty       = "roles"
+	permissionsProperty = "permissions"
+)
+
+// helper function to get list of strings stored in claims properties
+func claimsList(claims *auth.Claims, name string) []string {
+	switch v := claims.Properties[name].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		// decoded from JSON token payload
+		list := make([]string, 0, len(v))
+		for _, i := range v {
+			if s, ok := i.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// middleware allowing request only when authenticated user has given permission
+func requirePermission(perm string, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := userClaims(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+
+		if !model.HasPermission(claimsList(claims, permissionsProperty), perm) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("Permission '%s' is required", perm))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
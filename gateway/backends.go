@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/client"
+	"github.com/gkarlik/quark-go-example/gateway/resilience"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gkarlik/quark-go/metrics"
+	sd "github.com/gkarlik/quark-go/service/discovery"
+	"github.com/gkarlik/quark-go/service/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// resilience settings shared by executors of all backend services, loaded in main
+var (
+	executorOptions []resilience.Option
+	breakerChanged  func(service string) resilience.StateChangeFunc
+)
+
+// helper function to load retry and circuit breaker settings from environment variables
+func loadResilienceSettings() {
+	attempts, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_RETRY_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		panic("Incorrect retry max attempts value!")
+	}
+	baseDelay, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_RETRY_BASE_DELAY"))
+	if err != nil {
+		panic("Incorrect retry base delay value!")
+	}
+	maxDelay, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_RETRY_MAX_DELAY"))
+	if err != nil {
+		panic("Incorrect retry max delay value!")
+	}
+	threshold, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_BREAKER_FAILURE_THRESHOLD"))
+	if err != nil || threshold < 1 {
+		panic("Incorrect circuit breaker failure threshold value!")
+	}
+	openTimeout, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_BREAKER_OPEN_TIMEOUT"))
+	if err != nil {
+		panic("Incorrect circuit breaker open timeout value!")
+	}
+
+	executorOptions = []resilience.Option{
+		resilience.Retry(resilience.RetryPolicy{
+			MaxAttempts: attempts,
+			BaseDelay:   baseDelay,
+			MaxDelay:    maxDelay,
+		}),
+		resilience.CircuitBreaker(resilience.BreakerPolicy{
+			FailureThreshold: threshold,
+			OpenTimeout:      openTimeout,
+		}),
+	}
+
+	// metrics are shared by all executors, so they are registered only once
+	transitions := map[resilience.State]metrics.Counter{
+		resilience.Closed:   srv.Metrics().CreateCounter("circuit_breaker_closed", "Number of circuit breaker transitions to closed state"),
+		resilience.Open:     srv.Metrics().CreateCounter("circuit_breaker_opened", "Number of circuit breaker transitions to open state"),
+		resilience.HalfOpen: srv.Metrics().CreateCounter("circuit_breaker_half_opened", "Number of circuit breaker transitions to half-open state"),
+	}
+	openCircuits := srv.Metrics().CreateGauge("circuit_breakers_open", "Number of open circuit breakers")
+
+	breakerChanged = func(service string) resilience.StateChangeFunc {
+		return func(backend string, from, to resilience.State) {
+			transitions[to].Inc()
+			if to == resilience.Open {
+				openCircuits.Inc()
+			} else if from == resilience.Open {
+				openCircuits.Dec()
+			}
+
+			srv.Log().WarnWithFields(logger.Fields{
+				"service": service,
+				"backend": backend,
+				"from":    from.String(),
+				"to":      to.String(),
+			}, "Circuit breaker state changed")
+		}
+	}
+}
+
+// helper function to create executor guarding calls to instances of backend service
+func newExecutor(service string, opts ...resilience.Option) *resilience.Executor {
+	opts = append(append([]resilience.Option{}, executorOptions...), opts...)
+	opts = append(opts, resilience.OnStateChange(breakerChanged(service)))
+
+	return resilience.NewExecutor(opts...)
+}
+
+// helper function to call HTTP service on instance taken from service discovery catalog and pass
+// request tracing span and deadline to it. Body is sent again when call is retried.
+func callHTTPBackend(ctx context.Context, e *resilience.Executor, service string, idempotent bool, method, path string, header http.Header, body []byte, span trace.Span) ([]byte, error) {
+	var data []byte
+	err := e.Do(ctx, idempotent, func() (string, error) {
+		addr, err := srv.Discovery().GetServiceAddress(sd.ByName(service))
+		if err != nil {
+			return "", err
+		}
+		if addr == nil {
+			return "", client.ErrNoInstances
+		}
+		return addr.Host, nil
+	}, func(host string) error {
+		var err error
+		data, err = callHTTPService(ctx, method, "http://"+host+path, header, bytes.NewReader(body), span)
+		return err
+	})
+	return data, err
+}
+
+// helper function to pass request tracing span, request ID and authenticated user to RPC service,
+// request deadline is propagated by gRPC
+func rpcContext(ctx context.Context, span trace.Span) context.Context {
+	md := metadata.Pairs(strings.ToLower(requestIDHeader), requestID(ctx))
+	if user := userName(ctx); user != "" {
+		md.Set(strings.ToLower(userHeader), user)
+	}
+	srv.Tracer().InjectSpan(span, opentracing.TextMap, quark.RPCMetadataCarrier{MD: &md})
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// helper function to call RPC service on one of pooled instances, retrying on other instances if needed
+func invokeRPC(ctx context.Context, e *resilience.Executor, pool *client.Pool, idempotent bool, call func(conn *grpc.ClientConn) error) error {
+	var conn *grpc.ClientConn
+	return e.Do(ctx, idempotent, func() (string, error) {
+		c, err := pool.Conn()
+		if err != nil {
+			return "", err
+		}
+		conn = c
+		return c.Target(), nil
+	}, func(string) error {
+		return call(conn)
+	})
+}
+
+// helper function to classify gRPC errors caused by unavailable or overloaded backend
+func transientRPCError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Unknown, codes.Internal, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// helper function to classify HTTP errors - responses with 4xx status are caused by the request itself
+func transientHTTPError(err error) bool {
+	if e, ok := err.(*httpStatusError); ok {
+		return e.Status >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// gRPC status codes caused by the request itself rather than backend failure
+var clientErrorCodes = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.Unimplemented:      http.StatusNotImplemented,
+}
+
+// helper function to convert gRPC code name to error code, e.g. InvalidArgument to invalid_argument
+func grpcErrorCode(c codes.Code) string {
+	var b strings.Builder
+	for i, r := range c.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// helper function to write response for failed backend call - 503 with Retry-After
+// when circuit of the backend is open, 504 when request deadline passed, 4xx when backend
+// rejected the request, 500 otherwise
+func writeBackendError(w http.ResponseWriter, r *http.Request, service string, err error) {
+	httpStatus, e, ok := backendErrorResponse(r.Context(), service, err)
+	if !ok {
+		// client disconnected, nobody is waiting for the response
+		srv.Log().DebugWithFields(logger.Fields{"path": r.URL.Path}, "Request cancelled by client")
+		return
+	}
+
+	if oe, open := err.(*resilience.OpenError); open {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(oe.RetryAfter.Seconds()))))
+	}
+	writeErrorResponse(w, httpStatus, e)
+}
+
+// helper function to describe failed backend call with status and error envelope.
+// Reports false when context was cancelled, as there is nobody to describe failure to.
+func backendErrorResponse(ctx context.Context, service string, err error) (int, errorResponse, bool) {
+	if st, ok := status.FromError(err); ok {
+		if httpStatus, ok := clientErrorCodes[st.Code()]; ok {
+			return httpStatus, errorResponse{
+				Code:    grpcErrorCode(st.Code()),
+				Message: st.Message(),
+				Service: service,
+			}, true
+		}
+	}
+	if e, ok := err.(*httpStatusError); ok && e.Status < http.StatusInternalServerError {
+		code := statusCodes[e.Status]
+		if code == "" {
+			code = codeBadRequest
+		}
+		return e.Status, errorResponse{
+			Code:    code,
+			Message: e.Message,
+			Service: service,
+		}, true
+	}
+
+	if _, ok := err.(*resilience.OpenError); ok {
+		return http.StatusServiceUnavailable, errorResponse{
+			Code:    codeCircuitOpen,
+			Message: fmt.Sprintf("%s is temporarily unavailable", service),
+			Service: service,
+		}, true
+	}
+
+	switch ctx.Err() {
+	case context.Canceled:
+		return 0, errorResponse{}, false
+	case context.DeadlineExceeded:
+		srv.Log().WarnWithFields(logger.Fields{
+			"service":    service,
+			"request_id": requestID(ctx),
+			"error":      err,
+		}, "Request deadline exceeded")
+
+		return http.StatusGatewayTimeout, errorResponse{
+			Code:    codeUpstreamTimeout,
+			Message: fmt.Sprintf("%s did not respond in time", service),
+			Service: service,
+		}, true
+	}
+
+	srv.Log().ErrorWithFields(logger.Fields{
+		"service":    service,
+		"request_id": requestID(ctx),
+		"error":      err,
+	}, "Backend service call failed")
+
+	return http.StatusInternalServerError, errorResponse{
+		Code:    codeUpstreamError,
+		Message: fmt.Sprintf("%s call failed", service),
+		Service: service,
+	}, true
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gkarlik/quark-go/service/trace"
+)
+
+// batchItem is a single operation of the batch
+type batchItem struct {
+	Operation string  `json:"operation"`
+	Operands  []int64 `json:"operands"`
+}
+
+// batchRequest is a body of batch computation request
+type batchRequest struct {
+	Operations []batchItem `json:"operations"`
+}
+
+// batchResult is a result of single operation - either result or error is set
+type batchResult struct {
+	Index     int            `json:"index"`
+	Operation string         `json:"operation"`
+	Operands  []int64        `json:"operands"`
+	Result    *int64         `json:"result,omitempty"`
+	Error     *errorResponse `json:"error,omitempty"`
+}
+
+// batchResponse lists results in order of operations in the request
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// batchHandler executes operations of the batch on backend services - sums over single BatchSum stream
+// of SumService, multiplications by pool of workers
+type batchHandler struct {
+	*calculator
+
+	workers     int
+	itemTimeout time.Duration
+	maxItems    int
+}
+
+// helper function to create batch handler with settings loaded from environment variables
+func newBatchHandler(c *calculator) *batchHandler {
+	workers, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_BATCH_WORKERS"))
+	if err != nil || workers < 1 {
+		panic("Incorrect batch workers value!")
+	}
+	itemTimeout, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_BATCH_ITEM_TIMEOUT"))
+	if err != nil || itemTimeout <= 0 {
+		panic("Incorrect batch item timeout value!")
+	}
+	maxItems, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_BATCH_MAX_ITEMS"))
+	if err != nil || maxItems < 1 {
+		panic("Incorrect batch max items value!")
+	}
+
+	return &batchHandler{
+		calculator:  c,
+		workers:     workers,
+		itemTimeout: itemTimeout,
+		maxItems:    maxItems,
+	}
+}
+
+// ServeHTTP handles batch computation request. Failure of single operation is reported in its result
+// and does not fail the whole batch.
+func (h *batchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// report response time for monitoring purposes
+	start := time.Now()
+	defer func() {
+		responseTimeGauge.Set(float64(time.Since(start).Nanoseconds()))
+	}()
+
+	span := srv.Tracer().StartSpan("batch_request")
+	defer span.Finish()
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := h.validate(req); len(errs) > 0 {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, errorResponse{Message: "Validation failed", Errors: errs})
+		return
+	}
+	span.SetTag("operations", len(req.Operations))
+
+	results := make([]batchResult, len(req.Operations))
+	var sums, products []int
+	for i, item := range req.Operations {
+		results[i] = batchResult{
+			Index:     i,
+			Operation: item.Operation,
+			Operands:  item.Operands,
+		}
+
+		if item.Operation == sumOperation {
+			sums = append(sums, i)
+		} else {
+			products = append(products, i)
+		}
+	}
+
+	// sums and multiplications write results of their own items only, so results need no locking
+	var wg sync.WaitGroup
+	if len(sums) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			h.sumItems(r.Context(), span, req.Operations, sums, results)
+		}()
+	}
+
+	workers := h.workers
+	if len(products) < workers {
+		workers = len(products)
+	}
+	items := make(chan int)
+
+	wg.Add(workers)
+	for n := 0; n < workers; n++ {
+		go func() {
+			defer wg.Done()
+
+			for i := range items {
+				h.multiplyItem(r.Context(), span, req.Operations[i], &results[i])
+			}
+		}()
+	}
+
+	for _, i := range products {
+		items <- i
+	}
+	close(items)
+	wg.Wait()
+
+	if r.Context().Err() == context.Canceled {
+		srv.Log().DebugWithFields(logger.Fields{"path": r.URL.Path}, "Request cancelled by client")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, batchResponse{Results: results})
+}
+
+// helper function to validate operations of the batch
+func (h *batchHandler) validate(req batchRequest) model.ValidationErrors {
+	switch {
+	case len(req.Operations) == 0:
+		return model.ValidationErrors{{Field: "operations", Message: "must not be empty"}}
+	case len(req.Operations) > h.maxItems:
+		return model.ValidationErrors{{Field: "operations", Message: fmt.Sprintf("must contain at most %d items", h.maxItems)}}
+	}
+
+	var errs model.ValidationErrors
+	for i, item := range req.Operations {
+		if item.Operation != sumOperation && item.Operation != multiplyOperation {
+			errs = append(errs, model.ValidationError{
+				Field:   fmt.Sprintf("operations[%d].operation", i),
+				Message: fmt.Sprintf("must be %s or %s", sumOperation, multiplyOperation),
+			})
+		}
+		if len(item.Operands) != 2 {
+			errs = append(errs, model.ValidationError{
+				Field:   fmt.Sprintf("operations[%d].operands", i),
+				Message: "must contain 2 numbers",
+			})
+		}
+	}
+	return errs
+}
+
+// function to execute sums of the batch over single stream, each within item timeout. Failure
+// of the whole stream is reported in result of every sum.
+func (h *batchHandler) sumItems(ctx context.Context, parent trace.Span, items []batchItem, indexes []int, results []batchResult) {
+	span := srv.Tracer().StartSpanWithParent("batch_sum", parent)
+	defer span.Finish()
+	span.SetTag("operations", len(indexes))
+
+	operands := make([][2]int64, len(indexes))
+	for k, i := range indexes {
+		operands[k] = [2]int64{items[i].Operands[0], items[i].Operands[1]}
+	}
+
+	sums, errs, err := h.callBatchSum(ctx, span, operands, h.itemTimeout)
+	for k, i := range indexes {
+		switch {
+		case err != nil:
+			setBatchError(ctx, sumServiceName, err, &results[i])
+		case errs[k] != nil:
+			setBatchError(ctx, sumServiceName, errs[k], &results[i])
+		default:
+			sum := sums[k]
+			results[i].Result = &sum
+		}
+	}
+}
+
+// function to execute single multiplication of the batch within its own timeout
+func (h *batchHandler) multiplyItem(ctx context.Context, parent trace.Span, item batchItem, res *batchResult) {
+	span := srv.Tracer().StartSpanWithParent("batch_item", parent)
+	defer span.Finish()
+
+	ctx, cancel := context.WithTimeout(ctx, h.itemTimeout)
+	defer cancel()
+
+	result, err := h.callMultiply(ctx, span, item.Operands[0], item.Operands[1])
+	if err != nil {
+		setBatchError(ctx, multiplyServiceName, err, res)
+		return
+	}
+	res.Result = &result
+}
+
+// helper function to describe failure of the operation in its result. Cancelled items are not
+// described, response is not sent at all.
+func setBatchError(ctx context.Context, service string, err error, res *batchResult) {
+	if _, e, ok := backendErrorResponse(ctx, service, err); ok {
+		res.Error = &e
+	}
+}
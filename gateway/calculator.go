@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gkarlik/quark-go-example/gateway/client"
+	proxy "github.com/gkarlik/quark-go-example/gateway/proxies/sum"
+	"github.com/gkarlik/quark-go-example/gateway/resilience"
+	"github.com/gkarlik/quark-go-example/gateway/routing"
+	"github.com/gkarlik/quark-go/service/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backend services performing operations composed by the gateway
+const (
+	sumServiceName      = "SumService"
+	multiplyServiceName = "MultiplyService"
+)
+
+// operations performed by backend services
+const (
+	sumOperation      = "sum"
+	multiplyOperation = "multiply"
+)
+
+// calculator performs sums on SumService over gRPC and multiplications on MultiplyService over HTTP
+type calculator struct {
+	sum      *resilience.Executor
+	pool     *client.Pool
+	multiply *resilience.Executor
+}
+
+// helper function to create calculator, breakers are shared with routes of the same backend services
+func newCalculator() *calculator {
+	return &calculator{
+		sum:      srv.executor(routing.GRPC, sumServiceName),
+		pool:     srv.pool(sumServiceName),
+		multiply: srv.executor(routing.HTTP, multiplyServiceName),
+	}
+}
+
+// function to handle call to SumService
+func (c *calculator) callSum(ctx context.Context, span trace.Span, a, b int64) (int64, error) {
+	ctx = rpcContext(ctx, span)
+
+	var resp *proxy.SumResponse
+	err := invokeRPC(ctx, c.sum, c.pool, true, func(conn *grpc.ClientConn) error {
+		var err error
+		resp, err = proxy.NewSumServiceClient(conn).Sum(ctx, &proxy.SumRequest{A: a, B: b})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Sum, nil
+}
+
+// result of BatchSum stream received by the gateway
+type batchSumReply struct {
+	resp *proxy.BatchSumResponse
+	err  error
+}
+
+// function to sum pairs of operands over single BatchSum stream of SumService. Sums and errors of items
+// are returned in order of pairs, error of the whole stream is returned when any result is missing.
+// Items are summed one by one, so each result has to arrive within itemTimeout after the previous one,
+// otherwise remaining items fail with DeadlineExceeded error.
+func (c *calculator) callBatchSum(ctx context.Context, span trace.Span, operands [][2]int64, itemTimeout time.Duration) ([]int64, []error, error) {
+	ctx = rpcContext(ctx, span)
+
+	var (
+		sums []int64
+		errs []error
+	)
+	err := invokeRPC(ctx, c.sum, c.pool, true, func(conn *grpc.ClientConn) error {
+		// stream is cancelled when remaining items time out
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := proxy.NewSumServiceClient(conn).BatchSum(ctx)
+		if err != nil {
+			return err
+		}
+
+		// items are sent while results are received, so neither side waits for the other
+		sent := make(chan error, 1)
+		go func() {
+			for i, o := range operands {
+				if err := stream.Send(&proxy.BatchSumRequest{Index: int32(i), A: o[0], B: o[1]}); err != nil {
+					sent <- err
+					return
+				}
+			}
+			sent <- stream.CloseSend()
+		}()
+
+		replies := make(chan batchSumReply)
+		go func() {
+			for {
+				resp, err := stream.Recv()
+				select {
+				case replies <- batchSumReply{resp: resp, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		sums, errs = make([]int64, len(operands)), make([]error, len(operands))
+		done := make([]bool, len(operands))
+		received := 0
+
+		timeout := time.NewTimer(itemTimeout)
+		defer timeout.Stop()
+	receive:
+		for {
+			select {
+			case <-timeout.C:
+				for i := range operands {
+					if !done[i] {
+						errs[i] = status.Error(codes.DeadlineExceeded, "Batch item timed out")
+					}
+				}
+				return nil
+			case r := <-replies:
+				if r.err == io.EOF {
+					break receive
+				}
+				if r.err != nil {
+					return r.err
+				}
+
+				i := int(r.resp.Index)
+				if i < 0 || i >= len(operands) || done[i] {
+					return fmt.Errorf("Unexpected batch item %d", r.resp.Index)
+				}
+				if codes.Code(r.resp.Code) != codes.OK {
+					errs[i] = status.Error(codes.Code(r.resp.Code), r.resp.Error)
+				} else {
+					sums[i] = r.resp.Sum
+				}
+				done[i] = true
+				received++
+
+				if !timeout.Stop() {
+					<-timeout.C
+				}
+				timeout.Reset(itemTimeout)
+			}
+		}
+
+		if err := <-sent; err != nil {
+			return err
+		}
+		if received != len(operands) {
+			return fmt.Errorf("Batch sum returned %d of %d results", received, len(operands))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return sums, errs, nil
+}
+
+// function to handle call to MultiplyService
+func (c *calculator) callMultiply(ctx context.Context, span trace.Span, a, b int64) (int64, error) {
+	path := fmt.Sprintf("/multiply/%d/%d", a, b)
+
+	data, err := callHTTPBackend(ctx, c.multiply, multiplyServiceName, true, http.MethodGet, path, http.Header{"Accept": {jsonMediaType}}, nil, span)
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		Result int64 `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Result, nil
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/hashicorp/consul/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// ErrNoInstances is returned when there is no healthy instance of the service
+var ErrNoInstances = errors.New("No healthy service instances available")
+
+// delay before watching catalog again after Consul error
+const watchRetryDelay = 2 * time.Second
+
+// Pool keeps gRPC connections to all healthy instances of a service registered in Consul.
+// Connections are reused across requests and kept in sync with the catalog using blocking queries.
+// Calls are balanced across instances in round robin fashion.
+type Pool struct {
+	name     string
+	health   *api.Health
+	log      logger.Logger
+	dialOpts []grpc.DialOption
+
+	mu    sync.RWMutex
+	conns map[string]*grpc.ClientConn
+	addrs []string
+	next  uint64
+
+	cancel context.CancelFunc
+}
+
+// NewPool creates connection pool for service with given name, using Consul agent at consulAddr
+func NewPool(consulAddr, name string, log logger.Logger, opts ...grpc.DialOption) (*Pool, error) {
+	cfg := api.DefaultConfig()
+	cfg.Address = consulAddr
+
+	c, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		name:     name,
+		health:   c.Health(),
+		log:      log,
+		dialOpts: opts,
+		conns:    map[string]*grpc.ClientConn{},
+		cancel:   cancel,
+	}
+
+	// initial, non-blocking lookup so pool is usable right away
+	index, err := p.refresh(ctx, 0)
+	if err != nil {
+		log.ErrorWithFields(logger.Fields{
+			"service": name,
+			"error":   err,
+		}, "Cannot discover service instances")
+	}
+
+	go p.watch(ctx, index)
+
+	return p, nil
+}
+
+// helper function to query healthy instances and update connections
+func (p *Pool) refresh(ctx context.Context, index uint64) (uint64, error) {
+	opts := (&api.QueryOptions{WaitIndex: index}).WithContext(ctx)
+
+	entries, meta, err := p.health.Service(p.name, "", true, opts)
+	if err != nil {
+		return index, err
+	}
+
+	addrs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(e.Service.Port)))
+	}
+	p.update(addrs)
+
+	return meta.LastIndex, nil
+}
+
+// helper function to watch catalog changes until pool is disposed
+func (p *Pool) watch(ctx context.Context, index uint64) {
+	for ctx.Err() == nil {
+		var err error
+		if index, err = p.refresh(ctx, index); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			p.log.ErrorWithFields(logger.Fields{
+				"service": p.name,
+				"error":   err,
+			}, "Cannot watch service instances")
+
+			index = 0
+			time.Sleep(watchRetryDelay)
+		}
+	}
+}
+
+// helper function to dial new instances and close connections to the removed ones
+func (p *Pool) update(addrs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]bool, len(addrs))
+	active := make([]string, 0, len(addrs))
+
+	for _, addr := range addrs {
+		current[addr] = true
+
+		if _, ok := p.conns[addr]; !ok {
+			// dial does not block - connection is established in background
+			conn, err := grpc.Dial(addr, p.dialOpts...)
+			if err != nil {
+				p.log.ErrorWithFields(logger.Fields{
+					"service": p.name,
+					"addr":    addr,
+					"error":   err,
+				}, "Cannot connect to service instance")
+				continue
+			}
+			p.conns[addr] = conn
+
+			p.log.InfoWithFields(logger.Fields{
+				"service": p.name,
+				"addr":    addr,
+			}, "Service instance added")
+		}
+		active = append(active, addr)
+	}
+
+	for addr, conn := range p.conns {
+		if !current[addr] {
+			conn.Close()
+			delete(p.conns, addr)
+
+			p.log.InfoWithFields(logger.Fields{
+				"service": p.name,
+				"addr":    addr,
+			}, "Service instance removed")
+		}
+	}
+
+	p.addrs = active
+}
+
+// Conn returns connection to next healthy instance. Instances whose connection
+// is failing are skipped as long as there is any other one.
+func (p *Pool) Conn() (*grpc.ClientConn, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n := len(p.addrs)
+	if n == 0 {
+		return nil, ErrNoInstances
+	}
+
+	start := atomic.AddUint64(&p.next, 1)
+	for i := 0; i < n; i++ {
+		conn := p.conns[p.addrs[(start+uint64(i))%uint64(n)]]
+		if s := conn.GetState(); s != connectivity.TransientFailure && s != connectivity.Shutdown {
+			return conn, nil
+		}
+	}
+	return p.conns[p.addrs[start%uint64(n)]], nil
+}
+
+// Size returns number of instances in the pool
+func (p *Pool) Size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.addrs)
+}
+
+// Dispose stops watching catalog and closes all connections
+func (p *Pool) Dispose() {
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for addr, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+	p.addrs = nil
+}
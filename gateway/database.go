@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+	"github.com/gkarlik/quark-go/logger"
+)
+
+// database connection settings
+const (
+	dbConnectAttempts      = 10
+	dbConnectRetryDelay    = 3 * time.Second
+	dbStatsReportingPeriod = 5 * time.Second
+)
+
+// Database returns long-lived, pooled database context shared by all requests
+func (g *gateway) Database() rdbms.DbContext {
+	return g.db
+}
+
+// Dispose closes connection pools, broker connection and releases service resources
+func (g *gateway) Dispose() {
+	for _, p := range g.pools {
+		p.Dispose()
+	}
+	if g.db != nil {
+		g.db.Dispose()
+	}
+	g.events.Close()
+	g.ServiceBase.Dispose()
+}
+
+// helper function to check database connection
+func databaseCheck(ctx context.Context) error {
+	return srv.db.DB.DB().PingContext(ctx)
+}
+
+// helper function to open database connection pool configured from environment variables.
+// Database container may start later than gateway, so connection is retried.
+func openDatabase() *gorm.DbContext {
+	dialect := quark.GetEnvVar("GATEWAY_DB_DIALECT")
+	dbConnStr := quark.GetEnvVar("GATEWAY_DB_CONN_STR")
+
+	maxOpen, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_DB_MAX_OPEN_CONNS"))
+	if err != nil {
+		panic("Incorrect max open connections value!")
+	}
+	maxIdle, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_DB_MAX_IDLE_CONNS"))
+	if err != nil {
+		panic("Incorrect max idle connections value!")
+	}
+	lifetime, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_DB_CONN_MAX_LIFETIME"))
+	if err != nil {
+		panic("Incorrect connection max lifetime value!")
+	}
+
+	for attempt := 1; ; attempt++ {
+		context, err := gorm.NewDbContext(dialect, dbConnStr)
+		if err == nil {
+			context.DB.SingularTable(true)
+
+			pool := context.DB.DB()
+			pool.SetMaxOpenConns(maxOpen)
+			pool.SetMaxIdleConns(maxIdle)
+			pool.SetConnMaxLifetime(lifetime)
+
+			return context
+		}
+
+		srv.Log().ErrorWithFields(logger.Fields{
+			"error":   err,
+			"attempt": attempt,
+		}, "Cannot connect to database")
+
+		if attempt == dbConnectAttempts {
+			panic("Cannot connect to database!")
+		}
+		time.Sleep(dbConnectRetryDelay)
+	}
+}
+
+// helper function to periodically report connection pool statistics
+func reportDatabaseStats(context *gorm.DbContext) {
+	var (
+		open     = srv.Metrics().CreateGauge("db_open_connections", "Number of open database connections")
+		inUse    = srv.Metrics().CreateGauge("db_in_use_connections", "Number of database connections in use")
+		idle     = srv.Metrics().CreateGauge("db_idle_connections", "Number of idle database connections")
+		waitCnt  = srv.Metrics().CreateGauge("db_wait_count", "Total number of waits for database connection")
+		waitTime = srv.Metrics().CreateGauge("db_wait_duration", "Total time blocked waiting for database connection in nanoseconds")
+	)
+
+	for {
+		stats := context.DB.DB().Stats()
+
+		open.Set(float64(stats.OpenConnections))
+		inUse.Set(float64(stats.InUse))
+		idle.Set(float64(stats.Idle))
+		waitCnt.Set(float64(stats.WaitCount))
+		waitTime.Set(float64(stats.WaitDuration.Nanoseconds()))
+
+		time.Sleep(dbStatsReportingPeriod)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gkarlik/quark-go/service/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// header carrying time left to the request deadline to HTTP services. Relative timeout
+// is passed instead of absolute time, so clock skew between hosts does not matter.
+const timeoutHeader = "X-Request-Timeout"
+
+// middleware bounding request processing time. Deadline is derived from the request context,
+// so work is cancelled as well when client disconnects.
+func withTimeout(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// httpStatusError is returned when HTTP service responds with status other than 200
+type httpStatusError struct {
+	Status  int
+	Message string
+	url     string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s returned %d %s", e.url, e.Status, http.StatusText(e.Status))
+}
+
+// helper function to call HTTP service within context deadline and pass request tracing span
+// and request ID to it
+func callHTTPService(ctx context.Context, method, url string, header http.Header, body io.Reader, span trace.Span) ([]byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if id := requestID(ctx); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+	if user := userName(ctx); user != "" {
+		req.Header.Set(userHeader, user)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(timeoutHeader, time.Until(deadline).String())
+	}
+	if err := srv.Tracer().InjectSpan(span, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)); err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{
+			Status:  resp.StatusCode,
+			Message: strings.TrimSpace(string(data)),
+			url:     method + " " + url,
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gkarlik/quark-go-example/gateway/expr"
+	"github.com/gkarlik/quark-go/service/trace"
+)
+
+// evalRequest is a body of expression evaluation request
+type evalRequest struct {
+	Expression string `json:"expression"`
+}
+
+// evalStep describes single operation performed by backend service during evaluation
+type evalStep struct {
+	Step       int     `json:"step"`
+	Expression string  `json:"expression"`
+	Operation  string  `json:"operation"`
+	Operands   []int64 `json:"operands"`
+	Result     int64   `json:"result"`
+	Service    string  `json:"service"`
+	Duration   float64 `json:"duration_ms"`
+}
+
+// evalResponse is a result of expression evaluation with steps in order of completion
+type evalResponse struct {
+	Expression string     `json:"expression"`
+	Result     int64      `json:"result"`
+	Steps      []evalStep `json:"steps"`
+}
+
+// evalHandler evaluates expressions calling SumService over gRPC and MultiplyService over HTTP
+type evalHandler struct {
+	*calculator
+}
+
+// evaluation keeps state of single expression evaluation
+type evaluation struct {
+	*evalHandler
+	span trace.Span
+
+	sync.Mutex
+	steps   []evalStep
+	failure error
+}
+
+// backendError remembers backend service which failed evaluation step
+type backendError struct {
+	service string
+	err     error
+}
+
+func (e *backendError) Error() string {
+	return fmt.Sprintf("%s: %v", e.service, e.err)
+}
+
+// ServeHTTP handles expression evaluation request
+func (h *evalHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// report response time for monitoring purposes
+	start := time.Now()
+	defer func() {
+		responseTimeGauge.Set(float64(time.Since(start).Nanoseconds()))
+	}()
+
+	// all steps are reported as children of single evaluation span
+	span := srv.Tracer().StartSpan("eval_request")
+	defer span.Finish()
+
+	var req evalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	root, err := expr.Parse(req.Expression)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	span.SetTag("expression", root.String())
+
+	e := &evaluation{evalHandler: h, span: span, steps: []evalStep{}}
+	result, err := e.eval(r.Context(), root)
+	if err != nil {
+		be := err.(*backendError)
+		writeBackendError(w, r, be.service, be.err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, evalResponse{
+		Expression: root.String(),
+		Result:     result,
+		Steps:      e.steps,
+	})
+}
+
+// function to evaluate expression node, independent sub-expressions are evaluated in parallel.
+// Failure of any step cancels evaluation of other sub-expressions.
+func (e *evaluation) eval(ctx context.Context, n expr.Node) (int64, error) {
+	b, ok := n.(*expr.Binary)
+	if !ok {
+		return n.(*expr.Number).Value, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		left, right       int64
+		leftErr, rightErr error
+		wg                sync.WaitGroup
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if left, leftErr = e.eval(ctx, b.Left); leftErr != nil {
+			cancel()
+		}
+	}()
+	if right, rightErr = e.eval(ctx, b.Right); rightErr != nil {
+		cancel()
+	}
+	wg.Wait()
+
+	if leftErr != nil || rightErr != nil {
+		return 0, e.firstFailure()
+	}
+	return e.step(ctx, b, left, right)
+}
+
+// function to perform operation of expression node on evaluated operands
+func (e *evaluation) step(ctx context.Context, b *expr.Binary, x, y int64) (int64, error) {
+	span := srv.Tracer().StartSpanWithParent("eval_step", e.span)
+	defer span.Finish()
+
+	expression := b.String()
+	span.SetTag("expression", expression)
+
+	start := time.Now()
+
+	var (
+		result             int64
+		operation, service string
+		err                error
+	)
+	switch b.Op {
+	case expr.Add:
+		operation, service = sumOperation, sumServiceName
+		result, err = e.callSum(ctx, span, x, y)
+	case expr.Multiply:
+		operation, service = multiplyOperation, multiplyServiceName
+		result, err = e.callMultiply(ctx, span, x, y)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	if err != nil {
+		// sub-expressions evaluated in parallel fail as well because of cancellation,
+		// so only the first failure is reported
+		if e.failure == nil {
+			e.failure = &backendError{service: service, err: err}
+		}
+		return 0, e.failure
+	}
+
+	e.steps = append(e.steps, evalStep{
+		Step:       len(e.steps) + 1,
+		Expression: expression,
+		Operation:  operation,
+		Operands:   []int64{x, y},
+		Result:     result,
+		Service:    service,
+		Duration:   float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond),
+	})
+	return result, nil
+}
+
+// helper function to get the first failure of evaluation
+func (e *evaluation) firstFailure() error {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.failure
+}
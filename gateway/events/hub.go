@@ -0,0 +1,179 @@
+package events
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a broker message delivered to subscribers
+type Event struct {
+	ID    uint64
+	Topic string
+	Data  string
+}
+
+// Subscription receives events of selected topics. Events channel is closed when subscription
+// is closed or subscriber has been dropped for not keeping up with events.
+type Subscription struct {
+	Events <-chan Event
+	// events published after given ID could not be replayed, so subscriber has to load
+	// current state again
+	Missed bool
+
+	events  chan Event
+	topics  map[string]bool
+	dropped bool
+	hub     *Hub
+}
+
+// Dropped reports whether subscriber has been disconnected because its buffer was full
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	return s.dropped
+}
+
+// Close unregisters subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
+
+func (s *Subscription) wants(topic string) bool {
+	return len(s.topics) == 0 || s.topics[topic]
+}
+
+// Hub fans out events to subscribers and keeps the most recent events, so reconnecting
+// subscribers can catch up on events they missed. Events are numbered by each hub separately,
+// so only subscribers reconnecting to the same hub can catch up.
+type Hub struct {
+	mu          sync.Mutex
+	instance    string
+	lastID      uint64
+	replay      []Event
+	next        int
+	bufferSize  int
+	subscribers map[*Subscription]bool
+}
+
+// NewHub creates hub keeping replaySize recent events and buffering up to bufferSize events
+// of every subscriber. Event IDs are prefixed with hub instance created from current time, so IDs
+// given by subscribers connected to other hub or before restart of the hub are recognized.
+func NewHub(replaySize, bufferSize int) *Hub {
+	return &Hub{
+		instance:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		replay:      make([]Event, 0, replaySize),
+		bufferSize:  bufferSize,
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+// Publish assigns ID to the event, keeps it for replay and delivers it to interested subscribers.
+// Publishing never blocks - subscribers which buffers are full are dropped.
+func (h *Hub) Publish(topic, data string) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	e := Event{ID: h.lastID, Topic: topic, Data: data}
+
+	if cap(h.replay) > 0 {
+		if len(h.replay) < cap(h.replay) {
+			h.replay = append(h.replay, e)
+		} else {
+			h.replay[h.next] = e
+			h.next = (h.next + 1) % len(h.replay)
+		}
+	}
+
+	for s := range h.subscribers {
+		if !s.wants(topic) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			s.dropped = true
+			h.remove(s)
+		}
+	}
+	return e
+}
+
+// EventID returns identifier of the event given to subscribers
+func (h *Hub) EventID(e Event) string {
+	return h.instance + "-" + strconv.FormatUint(e.ID, 10)
+}
+
+// helper function to parse identifier of event published by this hub
+func (h *Hub) parseEventID(id string) (uint64, bool) {
+	i := strings.LastIndex(id, "-")
+	if i < 0 || id[:i] != h.instance {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(id[i+1:], 10, 64)
+	return n, err == nil
+}
+
+// Subscribe registers subscriber of given topics, all topics when none given. Kept events
+// published after event given by lastEventID are delivered first, when it is empty none are replayed.
+// Subscription is marked as missing events when the event is unknown or events published after
+// it are no longer kept.
+func (h *Hub) Subscribe(topics []string, lastEventID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &Subscription{
+		topics: map[string]bool{},
+		hub:    h,
+	}
+	for _, t := range topics {
+		s.topics[t] = true
+	}
+
+	var missed []Event
+	if lastEventID != "" {
+		lastID, ok := h.parseEventID(lastEventID)
+		// events are numbered consecutively, so none is lost unless more events were published
+		// after lastID than are kept
+		s.Missed = !ok || lastID > h.lastID || h.lastID-lastID > uint64(len(h.replay))
+
+		for i := 0; !s.Missed && i < len(h.replay); i++ {
+			e := h.replay[(h.next+i)%len(h.replay)]
+			if e.ID > lastID && s.wants(e.Topic) {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	s.events = make(chan Event, len(missed)+h.bufferSize)
+	s.Events = s.events
+	for _, e := range missed {
+		s.events <- e
+	}
+
+	h.subscribers[s] = true
+	return s
+}
+
+// Subscribers returns number of registered subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers)
+}
+
+// helper function to unregister subscriber, must be called with lock held
+func (h *Hub) remove(s *Subscription) {
+	if h.subscribers[s] {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"strconv"
+	"testing"
+)
+
+// helper function to collect events buffered by subscription
+func received(s *Subscription) []string {
+	var data []string
+	for {
+		select {
+		case e, ok := <-s.Events:
+			if !ok {
+				return data
+			}
+			data = append(data, e.Data)
+		default:
+			return data
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplayKeepsMostRecentEventsInOrder(t *testing.T) {
+	h := NewHub(3, 10)
+
+	h.Publish("sum", "1")
+	second := h.Publish("sum", "2")
+	for _, d := range []string{"3", "4", "5"} {
+		h.Publish("sum", d)
+	}
+
+	s := h.Subscribe(nil, h.EventID(second))
+	defer s.Close()
+
+	if data := received(s); s.Missed || !equal(data, []string{"3", "4", "5"}) {
+		t.Errorf("Expected events 3, 4, 5, got %v (missed: %v)", data, s.Missed)
+	}
+}
+
+func TestReplayReportsMissedEvents(t *testing.T) {
+	h := NewHub(3, 10)
+
+	first := h.Publish("sum", "1")
+	for _, d := range []string{"2", "3", "4", "5"} {
+		h.Publish("sum", d)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{"events no longer kept", h.EventID(first)},
+		{"event of other hub", "other-" + strconv.FormatUint(first.ID, 10)},
+		{"event not published yet", h.EventID(Event{ID: first.ID + 10})},
+		{"invalid identifier", "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := h.Subscribe(nil, tt.id)
+			defer s.Close()
+
+			if !s.Missed {
+				t.Error("Expected subscription to miss events")
+			}
+			if data := received(s); len(data) != 0 {
+				t.Errorf("Expected no replay, got %v", data)
+			}
+		})
+	}
+}
+
+func TestReplayAfterLastID(t *testing.T) {
+	h := NewHub(10, 10)
+
+	h.Publish("sum", "1")
+	second := h.Publish("multiply", "2")
+	h.Publish("sum", "3")
+	h.Publish("multiply", "4")
+
+	s := h.Subscribe([]string{"multiply"}, h.EventID(second))
+	defer s.Close()
+
+	if data := received(s); !equal(data, []string{"4"}) {
+		t.Errorf("Expected event 4, got %v", data)
+	}
+
+	fresh := h.Subscribe(nil, "")
+	defer fresh.Close()
+
+	if data := received(fresh); fresh.Missed || len(data) != 0 {
+		t.Errorf("Expected no replay without last ID, got %v", data)
+	}
+}
+
+func TestReplayDisabled(t *testing.T) {
+	h := NewHub(0, 10)
+
+	first := h.Publish("sum", "1")
+
+	s := h.Subscribe(nil, h.EventID(first))
+	defer s.Close()
+	if s.Missed {
+		t.Error("Expected subscriber of the last event not to miss events")
+	}
+
+	h.Publish("sum", "2")
+	late := h.Subscribe(nil, h.EventID(first))
+	defer late.Close()
+
+	if data := received(late); !late.Missed || len(data) != 0 {
+		t.Errorf("Expected missed events without replay, got %v", data)
+	}
+}
+
+func TestPublishFiltersTopicsAndDropsSlowSubscribers(t *testing.T) {
+	h := NewHub(0, 1)
+
+	sums := h.Subscribe([]string{"sum"}, "")
+	defer sums.Close()
+	all := h.Subscribe(nil, "")
+
+	h.Publish("multiply", "1")
+	h.Publish("sum", "2")
+
+	if !all.Dropped() {
+		t.Error("Expected subscriber with full buffer to be dropped")
+	}
+	if data := received(all); !equal(data, []string{"1"}) {
+		t.Errorf("Expected buffered event 1, got %v", data)
+	}
+	if data := received(sums); !equal(data, []string{"2"}) {
+		t.Errorf("Expected event 2, got %v", data)
+	}
+	if n := h.Subscribers(); n != 1 {
+		t.Errorf("Expected 1 subscriber, got %d", n)
+	}
+
+	sums.Close()
+	if _, ok := <-sums.Events; ok {
+		t.Error("Expected events channel to be closed")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/consumer"
+	"github.com/gkarlik/quark-go-example/gateway/events"
+	"github.com/gkarlik/quark-go-example/gateway/messages"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gkarlik/quark-go/metrics"
+)
+
+// intervals of event streaming
+const (
+	keepAliveInterval   = 15 * time.Second
+	resubscribeInterval = 5 * time.Second
+	clientRetryInterval = 3 * time.Second
+)
+
+// eventMessage is a data of event sent to clients
+type eventMessage struct {
+	Topic string          `json:"topic"`
+	Value json.RawMessage `json:"value"`
+}
+
+// eventsHandler streams broker messages to clients as Server-Sent Events
+type eventsHandler struct {
+	hub    *events.Hub
+	topics map[string]bool
+
+	streamsGauge   metrics.Gauge
+	droppedCounter metrics.Counter
+}
+
+// helper function to create events handler subscribed to topics given in GATEWAY_EVENT_TOPICS
+// environment variable
+func newEventsHandler() *eventsHandler {
+	replaySize, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_EVENT_REPLAY_SIZE"))
+	if err != nil || replaySize < 0 {
+		panic("Incorrect event replay size value!")
+	}
+	bufferSize, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_EVENT_CLIENT_BUFFER"))
+	if err != nil || bufferSize < 1 {
+		panic("Incorrect event client buffer value!")
+	}
+
+	h := &eventsHandler{
+		hub:            events.NewHub(replaySize, bufferSize),
+		topics:         map[string]bool{},
+		streamsGauge:   srv.Metrics().CreateGauge("event_streams_open", "Number of open event streams"),
+		droppedCounter: srv.Metrics().CreateCounter("event_streams_dropped", "Number of event streams dropped for not keeping up with events"),
+	}
+	for _, topic := range strings.Split(quark.GetEnvVar("GATEWAY_EVENT_TOPICS"), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			h.topics[topic] = true
+		}
+	}
+	if len(h.topics) == 0 {
+		panic("Incorrect event topics value!")
+	}
+
+	for topic := range h.topics {
+		go h.subscribe(topic)
+	}
+	return h
+}
+
+// function to forward messages of the topic from broker to the hub until gateway shuts down,
+// subscription is renewed when broker connection is lost. Each gateway instance consumes
+// from its own queue, so every instance streams all messages.
+func (h *eventsHandler) subscribe(topic string) {
+	c := consumer.New(quark.GetEnvVar("BROKER"), messages.Exchange, topic, h.forward)
+
+	for {
+		err := c.Run(serviceCtx)
+		if serviceCtx.Err() != nil {
+			return
+		}
+		srv.Log().ErrorWithFields(logger.Fields{
+			"error": err,
+			"topic": topic,
+		}, "Cannot subscribe to messages")
+
+		select {
+		case <-serviceCtx.Done():
+			return
+		case <-time.After(resubscribeInterval):
+		}
+	}
+}
+
+// function to forward broker message to the hub
+func (h *eventsHandler) forward(ctx context.Context, msg consumer.Message) error {
+	h.hub.Publish(msg.Topic, messageValue(msg.Body))
+	return nil
+}
+
+// helper function to represent broker message value as JSON - JSON payloads are passed as they are,
+// other values as strings
+func messageValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if json.Valid([]byte(s)) {
+		return s
+	}
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// ServeHTTP handles event stream of the client. Topics are selected with topic query parameters
+// and client reconnecting with Last-Event-ID header receives events it missed.
+func (h *eventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	var topics []string
+	for _, param := range r.URL.Query()["topic"] {
+		for _, topic := range strings.Split(param, ",") {
+			if !h.topics[topic] {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Unknown topic '%s'", topic))
+				return
+			}
+			topics = append(topics, topic)
+		}
+	}
+
+	sub := h.hub.Subscribe(topics, r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	h.streamsGauge.Inc()
+	defer h.streamsGauge.Dec()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable buffering of reverse proxies
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", clientRetryInterval/time.Millisecond)
+	// events are replayed only by the instance which sent them and only while they are kept,
+	// otherwise client is asked to load current state again
+	if sub.Missed {
+		fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-serviceCtx.Done():
+			// client reconnects to another instance
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events:
+			if !ok {
+				if sub.Dropped() {
+					h.droppedCounter.Inc()
+
+					srv.Log().WarnWithFields(logger.Fields{"request_id": requestID(r.Context())}, "Slow event stream dropped")
+				}
+				return
+			}
+
+			data, _ := json.Marshal(eventMessage{Topic: e.Topic, Value: json.RawMessage(e.Data)})
+			if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", h.hub.EventID(e), data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
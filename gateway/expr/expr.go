@@ -0,0 +1,176 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// limits protecting backend services from huge expressions
+const (
+	MaxLength     = 1000
+	MaxOperations = 100
+)
+
+// Operator of binary operation
+type Operator byte
+
+// supported operators
+const (
+	Add      Operator = '+'
+	Multiply Operator = '*'
+)
+
+// Node is a node of expression syntax tree
+type Node interface {
+	String() string
+}
+
+// Number is an integer literal
+type Number struct {
+	Value int64
+}
+
+// String returns literal as written in expression
+func (n *Number) String() string {
+	return strconv.FormatInt(n.Value, 10)
+}
+
+// Binary is an operation on two sub-expressions
+type Binary struct {
+	Op          Operator
+	Left, Right Node
+}
+
+// String returns operation in infix notation, parentheses are added only where needed
+func (b *Binary) String() string {
+	return operand(b.Left, b.Op) + string(b.Op) + operand(b.Right, b.Op)
+}
+
+// helper function to format operand of the operator, sum is enclosed in parentheses when multiplied
+func operand(n Node, op Operator) string {
+	if b, ok := n.(*Binary); ok && b.Op == Add && op == Multiply {
+		return "(" + b.String() + ")"
+	}
+	return n.String()
+}
+
+// SyntaxError describes invalid expression. Position is counted from 1.
+type SyntaxError struct {
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+}
+
+// parser is a recursive descent parser of grammar:
+//
+//	expr   = term { "+" term }
+//	term   = factor { "*" factor }
+//	factor = [ "-" ] digits | "(" expr ")"
+type parser struct {
+	s          string
+	pos        int
+	operations int
+}
+
+// Parse builds syntax tree of arithmetic expression, e.g. (2+3)*4+5. Whitespace is ignored.
+func Parse(s string) (Node, error) {
+	if len(s) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength + 1, Message: fmt.Sprintf("Expression exceeds %d characters", MaxLength)}
+	}
+
+	p := &parser{s: s}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos < len(p.s) {
+		return nil, p.errorf("Unexpected '%c'", p.s[p.pos])
+	}
+	return n, nil
+}
+
+func (p *parser) expr() (Node, error) {
+	return p.binary(Add, p.term)
+}
+
+func (p *parser) term() (Node, error) {
+	return p.binary(Multiply, p.factor)
+}
+
+// helper function to parse left-associative chain of operations with the same operator
+func (p *parser) binary(op Operator, next func() (Node, error)) (Node, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpaces(); p.pos < len(p.s) && p.s[p.pos] == byte(op); p.skipSpaces() {
+		p.pos++
+
+		if p.operations++; p.operations > MaxOperations {
+			return nil, p.errorf("Expression exceeds %d operations", MaxOperations)
+		}
+
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (Node, error) {
+	p.skipSpaces()
+	if p.pos == len(p.s) {
+		return nil, p.errorf("Unexpected end of expression")
+	}
+
+	if p.s[p.pos] == '(' {
+		p.pos++
+
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpaces(); p.pos == len(p.s) || p.s[p.pos] != ')' {
+			return nil, p.errorf("Missing ')'")
+		}
+		p.pos++
+		return n, nil
+	}
+
+	start := p.pos
+	if p.s[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+
+	literal := p.s[start:p.pos]
+	if literal == "" || literal == "-" {
+		p.pos = start
+		return nil, p.errorf("Number or '(' expected")
+	}
+
+	v, err := strconv.ParseInt(literal, 10, 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("Number %s out of int64 range", literal)
+	}
+	return &Number{Value: v}, nil
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.pos + 1, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,90 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		tree  string
+	}{
+		{"42", "42"},
+		{"-7", "-7"},
+		{" 2 +\t3 ", "2+3"},
+		{"2+3*4", "2+3*4"},
+		{"(2+3)*4+5", "(2+3)*4+5"},
+		{"2*(3+4)", "2*(3+4)"},
+		{"((1))", "1"},
+		{"1+2+3", "1+2+3"},
+		{"-9223372036854775808", "-9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if s := n.String(); s != tt.tree {
+				t.Errorf("Expected %s, got %s", tt.tree, s)
+			}
+		})
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	n, err := Parse("2+3*4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, ok := n.(*Binary)
+	if !ok || b.Op != Add {
+		t.Fatalf("Expected sum at root, got %s", n)
+	}
+	if r, ok := b.Right.(*Binary); !ok || r.Op != Multiply {
+		t.Errorf("Expected multiplication on the right, got %s", b.Right)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		pos     int
+		message string
+	}{
+		{"", 1, "Unexpected end of expression"},
+		{"2+", 3, "Unexpected end of expression"},
+		{"2+*3", 3, "Number or '(' expected"},
+		{"-", 1, "Number or '(' expected"},
+		{"(2+3", 5, "Missing ')'"},
+		{"2 3", 3, "Unexpected '3'"},
+		{"2)", 2, "Unexpected ')'"},
+		{"99999999999999999999", 1, "out of int64 range"},
+		{strings.Repeat("1", MaxLength+1), MaxLength + 1, "exceeds 1000 characters"},
+		{strings.Repeat("1+", MaxOperations+1) + "1", 2*MaxOperations + 3, "exceeds 100 operations"},
+	}
+
+	for _, tt := range tests {
+		name := tt.input
+		if len(name) > 20 {
+			name = name[:20] + "..."
+		}
+
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			se, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Expected syntax error, got %v", err)
+			}
+			if se.Pos != tt.pos {
+				t.Errorf("Expected position %d, got %d", tt.pos, se.Pos)
+			}
+			if !strings.Contains(se.Message, tt.message) {
+				t.Errorf("Expected message '%s', got '%s'", tt.message, se.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// CheckTimeout limits single dependency check
+const CheckTimeout = 2 * time.Second
+
+// settings of checks registered in Consul, instance failing liveness check is deregistered after a while
+const (
+	ConsulCheckInterval   = "10s"
+	ConsulCheckTimeout    = "5s"
+	ConsulDeregisterAfter = "1m"
+)
+
+// Check reports error when dependency is not available
+type Check func(ctx context.Context) error
+
+// Checks are dependencies which have to be available to serve requests, keyed by name
+type Checks map[string]Check
+
+// Report describes state of dependencies, failed checks hold error message
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Run runs checks in parallel, each within CheckTimeout
+func (c Checks) Run(ctx context.Context) (Report, bool) {
+	report := Report{Status: "ready", Checks: map[string]string{}}
+	ready := true
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range c {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			result := "ok"
+			err := check(ctx)
+			if err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[name] = result
+			if err != nil {
+				report.Status, ready = "not_ready", false
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report, ready
+}
+
+// LivenessHandler handles liveness probe - service is alive as long as it responds
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// ReadinessHandler creates handler of readiness probe - service is ready when all dependencies
+// are available. Service being shut down is not ready regardless of dependencies.
+func ReadinessHandler(checks Checks, shuttingDown func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown() {
+			writeStatus(w, http.StatusServiceUnavailable, Report{Status: "shutting_down", Checks: map[string]string{}})
+			return
+		}
+
+		report, ready := checks.Run(r.Context())
+		if !ready {
+			writeStatus(w, http.StatusServiceUnavailable, report)
+			return
+		}
+		writeStatus(w, http.StatusOK, report)
+	}
+}
+
+// helper function to write JSON status document
+func writeStatus(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Broker creates check of broker availability. Broker client does not expose connection state,
+// so broker is considered available when it accepts connections.
+func Broker(brokerURL string) Check {
+	return func(ctx context.Context) error {
+		u, err := url.Parse(brokerURL)
+		if err != nil {
+			return err
+		}
+
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// helper function to create Consul client with requests bounded by check timeout
+func consulClient(addr string) (*api.Client, error) {
+	cfg := api.DefaultConfig()
+	cfg.Address = addr
+	cfg.HttpClient = &http.Client{Timeout: CheckTimeout}
+
+	return api.NewClient(cfg)
+}
+
+// Discovery creates check of service discovery availability - Consul is available
+// when its cluster has a leader
+func Discovery(addr string) Check {
+	return func(ctx context.Context) error {
+		c, err := consulClient(addr)
+		if err != nil {
+			return err
+		}
+
+		leader, err := c.Status().Leader()
+		if err != nil {
+			return err
+		}
+		if leader == "" {
+			return fmt.Errorf("Consul cluster has no leader")
+		}
+		return nil
+	}
+}
+
+// RegisterChecks registers checks of the service instance given by name and host in Consul, keyed
+// by kind of the check, e.g. readiness check, so only ready instances are returned by service discovery
+func RegisterChecks(addr, name, host string, checks map[string]api.AgentServiceCheck) error {
+	c, err := consulClient(addr)
+	if err != nil {
+		return err
+	}
+
+	services, err := c.Agent().Services()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range services {
+		if s.Service != name || net.JoinHostPort(s.Address, strconv.Itoa(s.Port)) != host {
+			continue
+		}
+
+		for kind, check := range checks {
+			err := c.Agent().CheckRegister(&api.AgentCheckRegistration{
+				ID:                s.ID + ":" + kind,
+				Name:              name + " " + kind,
+				ServiceID:         s.ID,
+				AgentServiceCheck: check,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("Service %s at %s is not registered", name, host)
+}
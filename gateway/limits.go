@@ -0,0 +1,201 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	"github.com/gkarlik/quark-go-example/gateway/ratelimit"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gkarlik/quark-go/metrics"
+)
+
+// how often plans are reloaded from the database, so limits tuned there are applied without restart
+const planSyncInterval = time.Minute
+
+// name of access token property holding plan of the user
+const planProperty = "plan"
+
+var (
+	plans   = &planList{plans: map[string]model.Plan{}}
+	limiter *ratelimit.Limiter
+
+	rateLimitedCounter metrics.Counter
+)
+
+// planList keeps plans loaded from the database
+type planList struct {
+	sync.RWMutex
+	plans map[string]model.Plan
+}
+
+func (pl *planList) get(name string) (model.Plan, bool) {
+	pl.RLock()
+	defer pl.RUnlock()
+
+	p, ok := pl.plans[name]
+	return p, ok
+}
+
+func (pl *planList) all() []model.Plan {
+	pl.RLock()
+	defer pl.RUnlock()
+
+	all := make([]model.Plan, 0, len(pl.plans))
+	for _, p := range pl.plans {
+		all = append(all, p)
+	}
+	return all
+}
+
+func (pl *planList) reload(plans []model.Plan) {
+	m := make(map[string]model.Plan, len(plans))
+	for _, p := range plans {
+		m[p.Name] = p
+	}
+
+	pl.Lock()
+	defer pl.Unlock()
+
+	pl.plans = m
+}
+
+// helper function to load plans from the database
+func loadPlans() {
+	all, err := model.NewPlanRepository(srv.Database()).FindAll()
+	if err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot load plans")
+		return
+	}
+	plans.reload(all)
+}
+
+// helper function to periodically reload plans from the database
+func syncPlans() {
+	for {
+		time.Sleep(planSyncInterval)
+		loadPlans()
+	}
+}
+
+// helper function to create limiter with store selected by GATEWAY_RATE_LIMIT_STORE environment variable.
+// Postgres store makes limits hold across all gateway instances.
+func createLimiter() *ratelimit.Limiter {
+	switch quark.GetEnvVar("GATEWAY_RATE_LIMIT_STORE") {
+	case "memory":
+		return ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+	case "postgres":
+		return ratelimit.NewLimiter(ratelimit.NewPostgresStore(srv.db.DB))
+	}
+	panic("Incorrect rate limit store value!")
+}
+
+// helper function to get limits of the plan, unlimited plan limits are skipped
+func planLimits(p model.Plan) []ratelimit.Limit {
+	var limits []ratelimit.Limit
+	for _, l := range []ratelimit.Limit{
+		{Period: ratelimit.Minute, Max: p.RequestsPerMinute},
+		{Period: ratelimit.Day, Max: p.DailyQuota},
+		{Period: ratelimit.Month, Max: p.MonthlyQuota},
+	} {
+		if l.Max > 0 {
+			limits = append(limits, l)
+		}
+	}
+	return limits
+}
+
+// helper function to get plan of given name. Built-in plan missing in the database keeps its built-in
+// limits, so missing row never raises limits of the client, e.g. anonymous one. Other plans removed
+// from the database fall back to free plan, so clients are never left unlimited by mistake.
+func clientPlan(name string) model.Plan {
+	if plan, ok := plans.get(name); ok {
+		return plan
+	}
+	if plan, ok := model.DefaultPlan(name); ok {
+		return plan
+	}
+	if plan, ok := plans.get(model.FreePlan); ok {
+		return plan
+	}
+	plan, _ := model.DefaultPlan(model.FreePlan)
+	return plan
+}
+
+// helper function to identify client and its plan - authenticated users are limited according
+// to plan from access token, other clients by IP address according to anonymous plan
+func rateLimitClient(r *http.Request) (string, string) {
+	if claims, ok := userClaims(r); ok {
+		plan, _ := claims.Properties[planProperty].(string)
+		if plan == "" {
+			plan = model.FreePlan
+		}
+		return "user:" + claims.Username, plan
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return "ip:" + ip, model.AnonymousPlan
+}
+
+// helper function to report limits of the most restrictive window in X-RateLimit-* headers
+func writeRateLimitHeaders(w http.ResponseWriter, res ratelimit.Result) {
+	if res.Remaining < 0 {
+		return
+	}
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+}
+
+// middleware limiting requests of clients according to their plans. Limits of the most restrictive
+// window are reported in X-RateLimit-* headers.
+func limitRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, name := rateLimitClient(r)
+
+		now := time.Now()
+		res, err := limiter.Allow(key, planLimits(clientPlan(name)), now)
+		if err != nil {
+			// unavailable store must not take the whole gateway down
+			srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot check rate limit")
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writeRateLimitHeaders(w, res)
+
+		if !res.Allowed {
+			rateLimitedCounter.Inc()
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.Reset.Sub(now).Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// middleware reporting limits of the client in X-RateLimit-* headers on routes which are not limited,
+// so every API response carries them. Requests are not counted.
+func reportRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, name := rateLimitClient(r)
+
+		res, err := limiter.Status(key, planLimits(clientPlan(name)), time.Now())
+		if err != nil {
+			srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot check rate limit")
+		} else {
+			writeRateLimitHeaders(w, res)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gkarlik/quark-go-example/gateway/model"
+)
+
+func TestClientPlanFallback(t *testing.T) {
+	prev := plans
+	defer func() { plans = prev }()
+
+	plans = &planList{plans: map[string]model.Plan{}}
+	plans.reload([]model.Plan{
+		{Name: model.FreePlan, RequestsPerMinute: 100},
+		{Name: "team", RequestsPerMinute: 1000},
+	})
+
+	tests := []struct {
+		name              string
+		requestsPerMinute int
+	}{
+		{"team", 1000},
+		{model.FreePlan, 100},
+		// built-in plan missing in the database keeps its own limits instead of free plan ones
+		{model.AnonymousPlan, 10},
+		// removed plan falls back to free plan
+		{"removed", 100},
+	}
+
+	for _, tt := range tests {
+		if p := clientPlan(tt.name); p.RequestsPerMinute != tt.requestsPerMinute {
+			t.Errorf("Plan %s: expected %d requests per minute, got %d", tt.name, tt.requestsPerMinute, p.RequestsPerMinute)
+		}
+	}
+
+	plans.reload(nil)
+	if p := clientPlan("removed"); p.RequestsPerMinute != 60 {
+		t.Errorf("Expected built-in free plan limits, got %d requests per minute", p.RequestsPerMinute)
+	}
+}
@@ -1,37 +1,43 @@
 package main
 
 import (
-	"errors"
-	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"time"
 
-	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/client"
+	"github.com/gkarlik/quark-go-example/gateway/health"
+	"github.com/gkarlik/quark-go-example/gateway/messages"
+	"github.com/gkarlik/quark-go-example/gateway/migrations"
 	"github.com/gkarlik/quark-go-example/gateway/model"
-	proxy "github.com/gkarlik/quark-go-example/gateway/proxies/sum"
-	"github.com/gkarlik/quark-go/data/access/rdbms"
+	// generated proxies register protobuf messages used by gRPC routes
+	_ "github.com/gkarlik/quark-go-example/gateway/proxies/sum"
+	"github.com/gkarlik/quark-go-example/gateway/publisher"
+	"github.com/gkarlik/quark-go-example/gateway/resilience"
+	"github.com/gkarlik/quark-go-example/gateway/routing"
+	"github.com/gkarlik/quark-go-example/gateway/shutdown"
 	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
 	"github.com/gkarlik/quark-go/logger"
 	"github.com/gkarlik/quark-go/metrics"
 	"github.com/gkarlik/quark-go/metrics/prometheus"
 	auth "github.com/gkarlik/quark-go/middleware/auth/jwt"
-	"github.com/gkarlik/quark-go/middleware/ratelimiter"
 	sd "github.com/gkarlik/quark-go/service/discovery"
 	"github.com/gkarlik/quark-go/service/discovery/consul"
 	"github.com/gkarlik/quark-go/service/trace/zipkin"
 	"github.com/gorilla/mux"
+	"github.com/hashicorp/consul/api"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
-	opentracing "github.com/opentracing/opentracing-go"
-	"golang.org/x/net/context"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/metadata"
 )
 
 // gateway service based on quark.ServiceBase
 type gateway struct {
 	*quark.ServiceBase
+	db        *gorm.DbContext
+	pools     map[string]*client.Pool
+	executors map[string]*resilience.Executor
+	events    *publisher.Publisher
 }
 
 var (
@@ -46,6 +52,7 @@ func createGateway() *gateway {
 	gp := quark.GetEnvVar("GATEWAY_PORT")
 	discovery := quark.GetEnvVar("DISCOVERY")
 	tAddr := quark.GetEnvVar("TRACER")
+	bAddr := quark.GetEnvVar("BROKER")
 
 	port, err := strconv.Atoi(gp)
 	if err != nil {
@@ -66,205 +73,202 @@ func createGateway() *gateway {
 			quark.Discovery(consul.NewServiceDiscovery(discovery)),
 			quark.Metrics(prometheus.NewMetricsExposer()),
 			quark.Tracer(zipkin.NewTracer(tAddr, name, addr))),
+		pools:     map[string]*client.Pool{},
+		executors: map[string]*resilience.Executor{},
+		events:    publisher.New(bAddr, messages.Exchange),
 	}
 	g.Log().SetLevel(logger.DebugLevel)
 
 	responseTimeGauge = g.Metrics().CreateGauge("response_time", "Request response time")
+	rateLimitedCounter = g.Metrics().CreateCounter("rate_limited_requests", "Number of requests rejected by rate limiter")
 
 	return g
 }
 
-func NewDbContext() rdbms.DbContext {
-	dialect := quark.GetEnvVar("GATEWAY_DB_DIALECT")
-	dbConnStr := quark.GetEnvVar("GATEWAY_DB_CONN_STR")
-
-	context, err := gorm.NewDbContext(dialect, dbConnStr)
+func InitializeDatabase() {
+	applied, err := newMigrator().Up()
+	for _, mig := range applied {
+		srv.Log().InfoWithFields(logger.Fields{
+			"version":     mig.Version,
+			"description": mig.Description,
+		}, "Migration applied")
+	}
 	if err != nil {
-		srv.Log().ErrorWithFields(logger.Fields{"error": err})
-		return nil
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot migrate database")
+		return
 	}
 
-	context.DB.SingularTable(true)
-
-	return context
+	if err := migrations.Seed(srv.Database(), quark.GetEnvVar("GATEWAY_SEED_USER_LOGIN"), quark.GetEnvVar("GATEWAY_SEED_USER_PASSWORD")); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot seed database")
+	}
 }
 
-func InitializeDatabase() {
-	context := NewDbContext()
-	if context != nil {
-		defer context.Dispose()
-
-		context.(*gorm.DbContext).DB.AutoMigrate(&model.User{})
+// gateway service, created in main so maintenance commands and tests do not depend on environment
+var srv *gateway
 
-		user := &model.User{
-			Login:    "test",
-			Password: "test",
-		}
+func main() {
+	srv = createGateway()
 
-		repo := model.NewUserRepository(context)
-		repo.Save(user)
+	// handle maintenance commands
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrateCommand(os.Args[2:]))
 	}
-}
 
-func authenticateUser(credentials auth.Credentials) (auth.Claims, error) {
-	context := NewDbContext()
-	if context == nil {
-		return auth.Claims{}, errors.New("Invalid username or password")
-	}
-	defer context.Dispose()
+	defer srv.Dispose()
 
-	repo := model.NewUserRepository(context)
-	user, err := repo.FindByLogin(credentials.Username)
-	if err != nil {
-		return auth.Claims{}, errors.New("Invalid username or password")
+	// load token settings
+	var err error
+	tokenSecret = quark.GetEnvVar("GATEWAY_SECRET")
+	if accessTokenTTL, err = time.ParseDuration(quark.GetEnvVar("GATEWAY_ACCESS_TOKEN_TTL")); err != nil {
+		panic("Incorrect access token TTL value!")
 	}
-
-	// this is simplication - password should be hashed and salted!
-	if user.Password == credentials.Password {
-		return auth.Claims{
-			Username: credentials.Username,
-			StandardClaims: jwt.StandardClaims{
-				Issuer:    srv.Info().Address.String(),
-				ExpiresAt: time.Now().Add(1 * time.Hour).Unix(),
-			},
-		}, nil
+	if refreshTokenTTL, err = time.ParseDuration(quark.GetEnvVar("GATEWAY_REFRESH_TOKEN_TTL")); err != nil {
+		panic("Incorrect refresh token TTL value!")
 	}
-	return auth.Claims{}, errors.New("Invalid username or password")
-}
-
-var srv = createGateway()
-
-func main() {
-	defer srv.Dispose()
 
 	// setup authentication middleware
-	secret := quark.GetEnvVar("GATEWAY_SECRET")
 	am := auth.NewAuthenticationMiddleware(
-		auth.WithSecret(secret),
-		auth.WithContextKey("USER_KEY"),
-		auth.WithAuthenticationFunc(func(credentials auth.Credentials) (auth.Claims, error) {
-			return authenticateUser(credentials)
-		}))
+		auth.WithSecret(tokenSecret),
+		auth.WithContextKey(userKey))
+
+	srv.Log().Info("Connecting to database")
+	srv.db = openDatabase()
+	go reportDatabaseStats(srv.db)
+
+	// dependencies required to serve requests
+	readiness := health.Checks{
+		"database":  databaseCheck,
+		"broker":    health.Broker(quark.GetEnvVar("BROKER")),
+		"discovery": health.Discovery(quark.GetEnvVar("DISCOVERY")),
+	}
 
 	srv.Log().Info("Initializing database schema and data")
 	InitializeDatabase()
 
-	// setup rate limiter middleware
-	rl := ratelimiter.NewRateLimiterMiddleware(1 * time.Second)
-
-	r := mux.NewRouter()
-	// HTTP handler for generating tokens
-	r.HandleFunc("/login", am.GenerateToken)
+	go syncRevokedTokens()
 
-	// setup routes to limit traffic and require authentication
-	r.Handle("/api/sum/{a:[0-9]+}/{b:[0-9]+}", rl.Handle(am.Authenticate(http.HandlerFunc(sumHandler))))
-	r.Handle("/api/mul/{a:[0-9]+}/{b:[0-9]+}", rl.Handle(am.Authenticate(http.HandlerFunc(multiplyHandler))))
-	r.Handle("/metrics", srv.Metrics().ExposeHandler())
+	// events stored in outbox are published until gateway shuts down
+	relay := newOutboxRelay()
+	relays.Add(1)
+	go func() {
+		defer relays.Done()
 
-	srv.Log().InfoWithFields(logger.Fields{
-		"addr": srv.Info().Address.Host,
-	}, "Service initialized. Listening for incomming connections")
-
-	srv.Log().Fatal(http.ListenAndServe(srv.Info().Address.Host, r))
-}
-
-// function to handle call to RPC service to sum two integers
-func sumHandler(w http.ResponseWriter, r *http.Request) {
-	// report response time for monitoring purposes
-	start := time.Now()
-	defer func() {
-		responseTimeGauge.Set(float64(time.Since(start).Nanoseconds()))
+		relay.run()
 	}()
 
-	// handle request tracing span
-	span := srv.Tracer().StartSpan("sum_get_request")
-	defer span.Finish()
-
-	vars := mux.Vars(r)
-	a, _ := strconv.Atoi(vars["a"])
-	b, _ := strconv.Atoi(vars["b"])
-
-	// get the address of SumService from service discovery catalog
-	url, err := srv.Discovery().GetServiceAddress(sd.ByName("SumService"))
-	if err != nil {
-		srv.Log().Error(err)
-
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
-	}
+	srv.Log().Info("Loading plans")
+	loadPlans()
+	go syncPlans()
+	limiter = createLimiter()
 
-	// connect to RPC service
-	conn, err := grpc.Dial(url.Host, grpc.WithInsecure())
+	// load routes proxied to backend services
+	routes, err := routing.Load(quark.GetEnvVar("GATEWAY_ROUTES"))
 	if err != nil {
-		srv.Log().Error(err)
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot load routes")
 
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
+		panic("Cannot load routes!")
 	}
-	defer conn.Close()
-
-	client := proxy.NewSumServiceClient(conn)
 
-	// pass request tracing span to RPC service
-	md := metadata.Pairs()
-	err = srv.Tracer().InjectSpan(span, opentracing.TextMap, quark.RPCMetadataCarrier{MD: &md})
-	ctx := metadata.NewOutgoingContext(context.Background(), md)
-
-	// call RPC service
-	result, err := client.Sum(ctx, &proxy.SumRequest{A: int64(a), B: int64(b)})
+	// setup retries and circuit breakers for calls to backend services
+	loadResilienceSettings()
 
+	evalTimeout, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_EVAL_TIMEOUT"))
 	if err != nil {
-		srv.Log().Error(err)
-
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
+		panic("Incorrect eval timeout value!")
+	}
+	batchTimeout, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_BATCH_TIMEOUT"))
+	if err != nil || batchTimeout <= 0 {
+		panic("Incorrect batch timeout value!")
+	}
+	gracePeriod, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_SHUTDOWN_GRACE_PERIOD"))
+	if err != nil || gracePeriod <= 0 {
+		panic("Incorrect shutdown grace period value!")
 	}
 
-	// generate response
-	resp := fmt.Sprintf("%d + %d = %d", a, b, result.Sum)
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(resp))
-}
-
-// function to handle call to HTTP service to multiply two integers
-func multiplyHandler(w http.ResponseWriter, r *http.Request) {
-	// report response time for monitoring purposes
-	start := time.Now()
-	defer func() {
-		responseTimeGauge.Set(float64(time.Since(start).Nanoseconds()))
-	}()
-
-	// handle request tracing span
-	span := srv.Tracer().StartSpan("mul_get_request")
-	defer span.Finish()
+	// helper function to require authentication and reject revoked tokens
+	authenticate := func(h http.Handler) http.Handler {
+		return withErrorEnvelope(am.Authenticate(bypassErrorEnvelope(checkRevoked(h))))
+	}
+	// helper function to limit traffic of anonymous clients
+	limit := func(h http.HandlerFunc) http.Handler {
+		return limitRequests(h)
+	}
+	// helper function to require authentication and limit traffic of the user
+	protect := func(h http.HandlerFunc) http.Handler {
+		return authenticate(limitRequests(h))
+	}
 
-	vars := mux.Vars(r)
-	a, _ := strconv.Atoi(vars["a"])
-	b, _ := strconv.Atoi(vars["b"])
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+
+	// HTTP handlers for generating and revoking tokens
+	r.Handle("/login", limit(loginHandler))
+	r.Handle("/token/refresh", limit(refreshTokenHandler)).Methods(http.MethodPost)
+	r.Handle("/logout", protect(logoutHandler)).Methods(http.MethodPost)
+
+	// user account management
+	r.Handle("/users", limit(registerUserHandler)).Methods(http.MethodPost)
+	r.Handle("/users/me", protect(profileHandler)).Methods(http.MethodGet)
+	r.Handle("/users/me", protect(deleteUserHandler)).Methods(http.MethodDelete)
+	r.Handle("/users/me/password", protect(changePasswordHandler)).Methods(http.MethodPut)
+
+	// setup routes proxied to backend services
+	registerRoutes(r, routes, authenticate)
+
+	// expression evaluation and batch computation fanning out to backend services
+	calc := newCalculator()
+	r.Handle("/api/eval", withTimeout(evalTimeout, protect(requirePermission(model.PermissionEval, &evalHandler{calc})))).Methods(http.MethodPost)
+	r.Handle("/api/batch", withTimeout(batchTimeout, protect(requirePermission(model.PermissionBatch, newBatchHandler(calc))))).Methods(http.MethodPost)
+	// WebSocket clients pass access token in query string
+	r.Handle("/api/ws/running-sum", tokenFromQuery(protect(requirePermission(model.PermissionSum, &runningSumHandler{calc})))).Methods(http.MethodGet)
+
+	// broker messages streamed as Server-Sent Events, EventSource passes access token in query string as well
+	r.Handle("/api/events", tokenFromQuery(protect(requirePermission(model.PermissionEvents, newEventsHandler())))).Methods(http.MethodGet)
+	r.Handle("/metrics", withErrorEnvelope(srv.Metrics().ExposeHandler()))
+
+	// liveness and readiness probes
+	r.HandleFunc("/healthz", health.LivenessHandler)
+	r.Handle("/readyz", health.ReadinessHandler(readiness, shuttingDown))
+
+	// register gateway in service discovery catalog together with readiness check
+	if err := srv.Discovery().RegisterService(sd.WithInfo(srv.Info())); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{
+			"err": err,
+		}, "Cannot register service")
+
+		panic("Cannot register service!")
+	}
+	// instance is deregistered only when it is not alive, unavailable dependencies just take it out of rotation
+	if err := health.RegisterChecks(quark.GetEnvVar("DISCOVERY"), srv.Info().Name, srv.Info().Address.Host, map[string]api.AgentServiceCheck{
+		"live": {
+			HTTP:                           "http://" + srv.Info().Address.Host + "/healthz",
+			Interval:                       health.ConsulCheckInterval,
+			Timeout:                        health.ConsulCheckTimeout,
+			DeregisterCriticalServiceAfter: health.ConsulDeregisterAfter,
+		},
+		"ready": {
+			HTTP:     "http://" + srv.Info().Address.Host + "/readyz",
+			Interval: health.ConsulCheckInterval,
+			Timeout:  health.ConsulCheckTimeout,
+		},
+	}); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot register health checks")
+	}
 
-	// get the address of SumService from service discovery catalog
-	url, err := srv.Discovery().GetServiceAddress(sd.ByName("MultiplyService"))
-	if err != nil {
-		srv.Log().Error(err)
+	srv.Log().InfoWithFields(logger.Fields{
+		"addr": srv.Info().Address.Host,
+	}, "Service initialized. Listening for incomming connections")
 
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
-	}
-	if url != nil {
-		// call HTTP service and pass request tracing span to it
-		data, err := quark.CallHTTPService(srv, http.MethodGet, fmt.Sprintf("http://%s/multiply/%d/%d", url.Host, a, b), nil, span)
-		if err != nil {
-			srv.Log().Error(err)
-
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+	server := &http.Server{Addr: srv.Info().Address.Host, Handler: withRequestID(r)}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			srv.Log().Fatal(err)
 		}
+	}()
 
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
+	sig := shutdown.WaitForSignal()
+	srv.Log().InfoWithFields(logger.Fields{"signal": sig}, "Shutting down")
 
-		return
-	}
-	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	shutdownGracefully(server, gracePeriod)
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/migrations"
+	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+)
+
+const migrateUsage = "Usage: gateway migrate up|down|status"
+
+// helper function to create migrator for gateway database
+func newMigrator() *migrations.Migrator {
+	return migrations.NewMigrator(srv.Database().(*gorm.DbContext).DB, migrations.All)
+}
+
+// function to handle "migrate up|down|status" command, returns process exit code
+func migrateCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	srv.db = openDatabase()
+	defer srv.Dispose()
+
+	m := newMigrator()
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+		for _, mig := range applied {
+			fmt.Printf("Applied %d: %s\n", mig.Version, mig.Description)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := migrations.Seed(srv.Database(), quark.GetEnvVar("GATEWAY_SEED_USER_LOGIN"), quark.GetEnvVar("GATEWAY_SEED_USER_PASSWORD")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		reverted, err := m.Down()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if reverted == nil {
+			fmt.Println("No migrations to revert")
+		} else {
+			fmt.Printf("Reverted %d: %s\n", reverted.Version, reverted.Description)
+		}
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tAPPLIED AT\tDESCRIPTION")
+		for _, s := range statuses {
+			at := "pending"
+			if s.Applied {
+				at = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, at, s.Description)
+		}
+		tw.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
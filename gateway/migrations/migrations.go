@@ -0,0 +1,149 @@
+package migrations
+
+// All lists gateway schema migrations. New migrations must be appended with higher version
+// and must never be edited once released.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "create user table",
+		// statements are idempotent, so databases created by gorm AutoMigrate are adopted as well;
+		// early versions inserted seed user on every start, so duplicated logins have to be resolved
+		// manually before unique index is created - migration fails listing them
+		Up: `
+			CREATE TABLE IF NOT EXISTS "user" (
+				id serial PRIMARY KEY,
+				login varchar(255),
+				password varchar(255)
+			);
+			ALTER TABLE "user" ADD COLUMN IF NOT EXISTS password_algorithm varchar(255);
+			ALTER TABLE "user" ADD COLUMN IF NOT EXISTS password_cost integer;
+			DO $$
+			DECLARE
+				conflicts text;
+			BEGIN
+				SELECT string_agg(format('%s (ids %s)', login, ids), ', ') INTO conflicts
+				FROM (SELECT login, string_agg(id::text, ', ' ORDER BY id) AS ids FROM "user" GROUP BY login HAVING count(*) > 1) d;
+				IF conflicts IS NOT NULL THEN
+					RAISE EXCEPTION 'Duplicated user logins: %', conflicts;
+				END IF;
+			END $$;
+			CREATE UNIQUE INDEX IF NOT EXISTS uix_user_login ON "user" (login);
+		`,
+		Down: `DROP TABLE IF EXISTS "user";`,
+	},
+	{
+		Version:     2,
+		Description: "create role tables",
+		Up: `
+			CREATE TABLE IF NOT EXISTS role (
+				id serial PRIMARY KEY,
+				name varchar(255),
+				permissions varchar(255)
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS uix_role_name ON role (name);
+			CREATE TABLE IF NOT EXISTS user_role (
+				user_id integer NOT NULL,
+				role_id integer NOT NULL,
+				PRIMARY KEY (user_id, role_id)
+			);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS user_role;
+			DROP TABLE IF EXISTS role;
+		`,
+	},
+	{
+		Version:     3,
+		Description: "create token tables",
+		Up: `
+			CREATE TABLE IF NOT EXISTS refresh_token (
+				id serial PRIMARY KEY,
+				user_id integer,
+				token_hash varchar(255),
+				family varchar(255),
+				expires_at timestamp with time zone,
+				revoked_at timestamp with time zone,
+				created_at timestamp with time zone
+			);
+			CREATE INDEX IF NOT EXISTS idx_refresh_token_user_id ON refresh_token (user_id);
+			CREATE UNIQUE INDEX IF NOT EXISTS uix_refresh_token_token_hash ON refresh_token (token_hash);
+			CREATE INDEX IF NOT EXISTS idx_refresh_token_family ON refresh_token (family);
+			CREATE TABLE IF NOT EXISTS revoked_token (
+				id serial PRIMARY KEY,
+				token_id varchar(255),
+				expires_at timestamp with time zone
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS uix_revoked_token_token_id ON revoked_token (token_id);
+			CREATE INDEX IF NOT EXISTS idx_revoked_token_expires_at ON revoked_token (expires_at);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS revoked_token;
+			DROP TABLE IF EXISTS refresh_token;
+		`,
+	},
+	{
+		Version:     4,
+		Description: "create plan table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS plan (
+				id serial PRIMARY KEY,
+				name varchar(255),
+				requests_per_minute integer NOT NULL DEFAULT 0,
+				daily_quota integer NOT NULL DEFAULT 0,
+				monthly_quota integer NOT NULL DEFAULT 0
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS uix_plan_name ON plan (name);
+			ALTER TABLE "user" ADD COLUMN IF NOT EXISTS plan_id integer REFERENCES plan (id) ON DELETE SET NULL;
+		`,
+		Down: `
+			ALTER TABLE "user" DROP COLUMN IF EXISTS plan_id;
+			DROP TABLE IF EXISTS plan;
+		`,
+	},
+	{
+		Version:     5,
+		Description: "create rate limit counter table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS rate_limit_counter (
+				id varchar(255) PRIMARY KEY,
+				count integer NOT NULL,
+				expires_at timestamp with time zone NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_rate_limit_counter_expires_at ON rate_limit_counter (expires_at);
+		`,
+		Down: `DROP TABLE IF EXISTS rate_limit_counter;`,
+	},
+	{
+		Version:     6,
+		Description: "create outbox table",
+		Up: `
+			CREATE TABLE IF NOT EXISTS outbox_message (
+				id bigserial PRIMARY KEY,
+				topic varchar(255) NOT NULL,
+				payload text NOT NULL,
+				attempts integer NOT NULL DEFAULT 0,
+				last_error text NOT NULL DEFAULT '',
+				created_at timestamp with time zone NOT NULL,
+				next_attempt_at timestamp with time zone NOT NULL,
+				sent_at timestamp with time zone
+			);
+			CREATE INDEX IF NOT EXISTS idx_outbox_message_pending ON outbox_message (next_attempt_at, id) WHERE sent_at IS NULL;
+			CREATE INDEX IF NOT EXISTS idx_outbox_message_sent_at ON outbox_message (sent_at);
+		`,
+		Down: `DROP TABLE IF EXISTS outbox_message;`,
+	},
+	{
+		Version:     7,
+		Description: "assign user role to accounts without roles",
+		// accounts created before roles were introduced; role is created here when it is not seeded yet,
+		// its permissions are set by seed
+		Up: `
+			INSERT INTO role (name, permissions) SELECT 'user', '' WHERE NOT EXISTS (SELECT 1 FROM role WHERE name = 'user');
+			INSERT INTO user_role (user_id, role_id)
+				SELECT u.id, r.id FROM "user" u, role r
+				WHERE r.name = 'user' AND u.id NOT IN (SELECT user_id FROM user_role);
+		`,
+		// assigned roles cannot be told apart from ones granted later, so they are kept
+		Down: ``,
+	},
+}
@@ -0,0 +1,192 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// arbitrary key of advisory lock serializing migrations run by concurrent gateway instances
+const lockKey = 7231605
+
+// Migration is a single, versioned schema change with SQL to apply and revert it.
+// Down is empty for data changes which cannot be reverted.
+type Migration struct {
+	Version     int64
+	Description string
+	Up          string
+	Down        string
+}
+
+// Status describes whether migration has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// schemaMigration is a row of schema_migrations table
+type schemaMigration struct {
+	Version     int64 `gorm:"primary_key"`
+	Description string
+	AppliedAt   time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies and reverts migrations in version order, tracking them in schema_migrations table
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator creates migrator for given migrations which must be ordered by version
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			panic(fmt.Sprintf("Migration %d is out of order!", migrations[i].Version))
+		}
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		description text NOT NULL,
+		applied_at timestamp with time zone NOT NULL
+	)`).Error
+}
+
+func (m *Migrator) applied() (map[int64]time.Time, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r.AppliedAt
+	}
+	return applied, nil
+}
+
+// helper function to run migration step in transaction holding migration lock.
+// Returns false when step became unnecessary because other instance already did it.
+func (m *Migrator) run(mig Migration, up bool) (bool, error) {
+	tx := m.db.Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	defer tx.Rollback()
+
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error; err != nil {
+		return false, err
+	}
+
+	var count int
+	if err := tx.Model(&schemaMigration{}).Where("version = ?", mig.Version).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if (count > 0) == up {
+		return false, nil
+	}
+
+	if up {
+		if err := tx.Exec(mig.Up).Error; err != nil {
+			return false, fmt.Errorf("Migration %d (%s) failed: %v", mig.Version, mig.Description, err)
+		}
+		row := &schemaMigration{Version: mig.Version, Description: mig.Description, AppliedAt: time.Now()}
+		if err := tx.Create(row).Error; err != nil {
+			return false, err
+		}
+	} else {
+		// migration without Down is just forgotten
+		if mig.Down != "" {
+			if err := tx.Exec(mig.Down).Error; err != nil {
+				return false, fmt.Errorf("Reverting migration %d (%s) failed: %v", mig.Version, mig.Description, err)
+			}
+		}
+		if err := tx.Where("version = ?", mig.Version).Delete(&schemaMigration{}).Error; err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Up applies all pending migrations and returns the applied ones
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		ok, err := m.run(mig, true)
+		if err != nil {
+			return done, err
+		}
+		if ok {
+			done = append(done, mig)
+		}
+	}
+	return done, nil
+}
+
+// Down reverts the most recently applied migration. Returns nil when there is nothing to revert,
+// also when other instance reverted the migration meanwhile.
+func (m *Migrator) Down() (*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+
+		ok, err := m.run(mig, false)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return &mig, nil
+	}
+	return nil, nil
+}
+
+// Status returns state of all known migrations
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		statuses = append(statuses, Status{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import "testing"
+
+func TestNewMigratorRejectsUnorderedMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int64
+	}{
+		{"descending", []int64{1, 3, 2}},
+		{"duplicated", []int64{1, 2, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected panic")
+				}
+			}()
+
+			var migs []Migration
+			for _, v := range tt.versions {
+				migs = append(migs, Migration{Version: v})
+			}
+			NewMigrator(nil, migs)
+		})
+	}
+}
+
+func TestAllMigrations(t *testing.T) {
+	NewMigrator(nil, All)
+
+	for _, mig := range All {
+		if mig.Description == "" || mig.Up == "" {
+			t.Errorf("Migration %d has no description or SQL", mig.Version)
+		}
+	}
+}
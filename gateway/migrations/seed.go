@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+)
+
+// Seed inserts data required by the gateway - built-in roles, plans and sample user with user role,
+// which is created only when login is given. Permissions of built-in roles are reset to the defaults, while existing
+// plans and users are left untouched, so it is safe to run it on every start.
+func Seed(c rdbms.DbContext, sampleLogin, samplePassword string) error {
+	roles := model.NewRoleRepository(c)
+	if _, err := roles.EnsureRole(model.UserRole,
+		model.PermissionSum,
+		model.PermissionMultiply,
+		model.PermissionSubtract,
+		model.PermissionDivide,
+		model.PermissionPower,
+		model.PermissionModulo,
+		model.PermissionNegate,
+		model.PermissionDecimal,
+		model.PermissionEval,
+		model.PermissionBatch,
+		model.PermissionEvents); err != nil {
+		return err
+	}
+	if _, err := roles.EnsureRole(model.AdminRole, model.PermissionAll); err != nil {
+		return err
+	}
+
+	plans := model.NewPlanRepository(c)
+	for _, p := range model.DefaultPlans {
+		if err := plans.EnsurePlan(&p); err != nil {
+			return err
+		}
+	}
+
+	if sampleLogin == "" {
+		return nil
+	}
+	if _, err := model.NewUserRepository(c).Create(sampleLogin, samplePassword); err != nil && err != model.ErrLoginTaken {
+		return err
+	}
+	return nil
+}
@@ -1,17 +1,33 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gkarlik/quark-go/data/access/rdbms"
 	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+	jinzhu "github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID       uint `gorm:"primary_key"`
-	Login    string
-	Password string
+	ID                uint   `gorm:"primary_key"`
+	Login             string `gorm:"unique_index"`
+	Password          string
+	PasswordAlgorithm string
+	PasswordCost      int
+	PlanID            *uint
+	Roles             []Role `gorm:"many2many:user_role"`
 }
 
+// IsNotFound reports whether error means that requested record does not exist, other errors
+// mean that database could not be queried
+func IsNotFound(err error) bool {
+	return err == jinzhu.ErrRecordNotFound
+}
+
+// ErrLoginTaken is returned when registering user with login which already exists
+var ErrLoginTaken = errors.New("Login is already taken")
+
 type UserRepository struct {
 	*gorm.RepositoryBase
 }
@@ -37,3 +53,125 @@ func (ur *UserRepository) FindByLogin(login string) (*User, error) {
 	}
 	return &user, nil
 }
+
+func (ur *UserRepository) FindByID(id uint) (*User, error) {
+	var user User
+	if err := ur.First(&user, id); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Create validates and registers new user with user role. Login uniqueness is enforced by the database,
+// so concurrent registrations of the same login result in ErrLoginTaken as well.
+func (ur *UserRepository) Create(login, password string) (*User, error) {
+	var errs ValidationErrors
+	if e := validateLogin("login", login); e != nil {
+		errs = append(errs, *e)
+	}
+	if e := validatePassword("password", password); e != nil {
+		errs = append(errs, *e)
+	}
+	if err := errs.orNil(); err != nil {
+		return nil, err
+	}
+
+	if _, err := ur.FindByLogin(login); err == nil {
+		return nil, ErrLoginTaken
+	}
+
+	user := &User{Login: login}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+
+	// user is saved together with its role, so failure leaves no account without permissions
+	err := InTransaction(ur.Context(), func(tx rdbms.DbContext) error {
+		if err := NewUserRepository(tx).Save(user); err != nil {
+			return err
+		}
+
+		roles := NewRoleRepository(tx)
+		role, err := roles.FindByName(UserRole)
+		if err != nil {
+			return err
+		}
+		return roles.AssignRole(user, role)
+	})
+	if err != nil {
+		// unique index violation when user was created in the meantime
+		if _, ferr := ur.FindByLogin(login); ferr == nil {
+			return nil, ErrLoginTaken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// LoadRoles loads roles assigned to user
+func (ur *UserRepository) LoadRoles(user *User) error {
+	db := ur.Context().(*gorm.DbContext).DB
+	return db.Model(user).Association("Roles").Find(&user.Roles).Error
+}
+
+// ChangePassword replaces user password after verifying the current one
+func (ur *UserRepository) ChangePassword(user *User, current, password string) error {
+	if !user.CheckPassword(current) {
+		return ErrInvalidCredentials
+	}
+
+	if e := validatePassword("new_password", password); e != nil {
+		return ValidationErrors{*e}
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	return ur.Save(user)
+}
+
+// DeleteUser removes user account
+func (ur *UserRepository) DeleteUser(user *User) error {
+	db := ur.Context().(*gorm.DbContext).DB
+	if err := db.Model(user).Association("Roles").Clear().Error; err != nil {
+		return err
+	}
+	return ur.Delete(user)
+}
+
+// PasswordUpgradeError is returned together with user whose credentials are valid, when outdated
+// password hash could not be replaced. It should not block login, as upgrade is retried next time.
+type PasswordUpgradeError struct {
+	Err error
+}
+
+func (e *PasswordUpgradeError) Error() string {
+	return "Cannot upgrade password hash: " + e.Err.Error()
+}
+
+// VerifyCredentials finds user by login and checks password. Outdated password hashes
+// (including legacy plain text ones) are transparently replaced with current ones.
+func (ur *UserRepository) VerifyCredentials(login, password string) (*User, error) {
+	user, err := ur.FindByLogin(login)
+	if err != nil {
+		// spend the same time as for existing user
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.CheckPassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.NeedsRehash() {
+		err := user.SetPassword(password)
+		if err == nil {
+			err = ur.Save(user)
+		}
+		if err != nil {
+			return user, &PasswordUpgradeError{Err: err}
+		}
+	}
+	return user, nil
+}
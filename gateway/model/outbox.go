@@ -0,0 +1,89 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+)
+
+// OutboxMessage is a broker message stored in the same transaction as domain changes it describes.
+// Messages are published by relay and marked as sent afterwards.
+type OutboxMessage struct {
+	ID            uint `gorm:"primary_key"`
+	Topic         string
+	Payload       string
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	SentAt        *time.Time
+}
+
+type OutboxRepository struct {
+	*gorm.RepositoryBase
+}
+
+func NewOutboxRepository(c rdbms.DbContext) *OutboxRepository {
+	repo := &OutboxRepository{
+		RepositoryBase: &gorm.RepositoryBase{},
+	}
+
+	repo.SetContext(c)
+
+	return repo
+}
+
+// Add stores message to be published, context has to be the transaction of domain changes
+func (or *OutboxRepository) Add(topic string, payload []byte) error {
+	now := time.Now()
+	return or.Save(&OutboxMessage{
+		Topic:         topic,
+		Payload:       string(payload),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	})
+}
+
+// ClaimPending claims up to limit messages due to be published until given time, in order they were added.
+// Claimed messages are not returned again before the claim ends, so relays of gateway instances do not publish
+// the same messages. Rows are locked only by the statement itself, messages locked by other relays are skipped.
+func (or *OutboxRepository) ClaimPending(limit int, now, until time.Time) ([]OutboxMessage, error) {
+	db := or.Context().(*gorm.DbContext).DB
+
+	var messages []OutboxMessage
+	err := db.Raw(`UPDATE outbox_message SET next_attempt_at = ? WHERE id IN (
+		SELECT id FROM outbox_message WHERE sent_at IS NULL AND next_attempt_at <= ? ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED
+	) RETURNING *`, until, now, limit).
+		Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// returned rows are not ordered
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+// MarkSent records that message has been published
+func (or *OutboxRepository) MarkSent(m *OutboxMessage, now time.Time) error {
+	m.Attempts++
+	m.LastError = ""
+	m.SentAt = &now
+	return or.Save(m)
+}
+
+// MarkFailed records failed attempt of publishing message, which is retried after given time
+func (or *OutboxRepository) MarkFailed(m *OutboxMessage, err error, next time.Time) error {
+	m.Attempts++
+	m.LastError = err.Error()
+	m.NextAttemptAt = next
+	return or.Save(m)
+}
+
+// DeleteSent removes messages sent before given time
+func (or *OutboxRepository) DeleteSent(before time.Time) error {
+	db := or.Context().(*gorm.DbContext).DB
+	return db.Where("sent_at < ?", before).Delete(&OutboxMessage{}).Error
+}
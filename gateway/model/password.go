@@ -0,0 +1,54 @@
+package model
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// supported password hashing algorithms
+const (
+	PlainTextAlgorithm = "plain"
+	BcryptAlgorithm    = "bcrypt"
+)
+
+// ErrInvalidCredentials is returned when login or password does not match
+var ErrInvalidCredentials = errors.New("Invalid username or password")
+
+// PasswordCost is bcrypt cost used for new hashes - stored hashes with lower cost are upgraded on next login
+var PasswordCost = bcrypt.DefaultCost
+
+// hash compared against when user does not exist, so response time does not reveal valid logins
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), PasswordCost)
+
+// SetPassword hashes password using current algorithm and cost
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return err
+	}
+
+	u.Password = string(hash)
+	u.PasswordAlgorithm = BcryptAlgorithm
+	u.PasswordCost = PasswordCost
+
+	return nil
+}
+
+// CheckPassword compares password with stored one in constant time
+func (u *User) CheckPassword(password string) bool {
+	switch u.PasswordAlgorithm {
+	case BcryptAlgorithm:
+		return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+	case PlainTextAlgorithm, "":
+		// legacy rows created before hashing was introduced
+		return subtle.ConstantTimeCompare([]byte(u.Password), []byte(password)) == 1
+	}
+	return false
+}
+
+// NeedsRehash reports whether stored password uses outdated algorithm or cost
+func (u *User) NeedsRehash() bool {
+	return u.PasswordAlgorithm != BcryptAlgorithm || u.PasswordCost < PasswordCost
+}
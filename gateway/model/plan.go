@@ -0,0 +1,94 @@
+package model
+
+import (
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+)
+
+// built-in plans
+const (
+	// AnonymousPlan applies to requests without authenticated user, limited per client IP
+	AnonymousPlan = "anonymous"
+	// FreePlan applies to users without plan assigned
+	FreePlan = "free"
+	ProPlan  = "pro"
+)
+
+// Plan defines request limits of its users. Zero means no limit.
+type Plan struct {
+	ID                uint   `gorm:"primary_key"`
+	Name              string `gorm:"unique_index"`
+	RequestsPerMinute int
+	DailyQuota        int
+	MonthlyQuota      int
+}
+
+// DefaultPlans are built-in plans seeded into the database
+var DefaultPlans = []Plan{
+	{Name: AnonymousPlan, RequestsPerMinute: 10, DailyQuota: 500, MonthlyQuota: 5000},
+	{Name: FreePlan, RequestsPerMinute: 60, DailyQuota: 10000, MonthlyQuota: 100000},
+	{Name: ProPlan, RequestsPerMinute: 600, MonthlyQuota: 5000000},
+}
+
+// DefaultPlan returns built-in plan of given name, reports false when there is no such plan
+func DefaultPlan(name string) (Plan, bool) {
+	for _, p := range DefaultPlans {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Plan{}, false
+}
+
+// PlanName returns name of plan assigned to user from given plans, free plan when none is assigned
+func (u *User) PlanName(plans []Plan) string {
+	if u.PlanID != nil {
+		for _, p := range plans {
+			if p.ID == *u.PlanID {
+				return p.Name
+			}
+		}
+	}
+	return FreePlan
+}
+
+type PlanRepository struct {
+	*gorm.RepositoryBase
+}
+
+func NewPlanRepository(c rdbms.DbContext) *PlanRepository {
+	repo := &PlanRepository{
+		RepositoryBase: &gorm.RepositoryBase{},
+	}
+
+	repo.SetContext(c)
+
+	return repo
+}
+
+func (pr *PlanRepository) FindByName(name string) (*Plan, error) {
+	var plan Plan
+	if err := pr.First(&plan, Plan{Name: name}); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// FindAll returns all plans
+func (pr *PlanRepository) FindAll() ([]Plan, error) {
+	var plans []Plan
+	if err := pr.Find(&plans); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// EnsurePlan creates plan when it does not exist. Limits of existing plan are left untouched,
+// so they can be tuned in the database.
+func (pr *PlanRepository) EnsurePlan(plan *Plan) error {
+	if existing, err := pr.FindByName(plan.Name); err == nil {
+		*plan = *existing
+		return nil
+	}
+	return pr.Save(plan)
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+)
+
+// permissions checked by gateway routes
+const (
+	PermissionAll      = "*"
+	PermissionSum      = "api:sum"
+	PermissionMultiply = "api:mul"
+	PermissionSubtract = "api:sub"
+	PermissionDivide   = "api:div"
+	PermissionPower    = "api:pow"
+	PermissionModulo   = "api:mod"
+	PermissionNegate   = "api:neg"
+	PermissionDecimal  = "api:decimal"
+	PermissionEval     = "api:eval"
+	PermissionBatch    = "api:batch"
+	PermissionEvents   = "api:events"
+)
+
+// built-in roles
+const (
+	UserRole  = "user"
+	AdminRole = "admin"
+)
+
+// Role groups permissions granted to users. Permissions are stored as comma separated list.
+type Role struct {
+	ID          uint   `gorm:"primary_key"`
+	Name        string `gorm:"unique_index"`
+	Permissions string
+}
+
+// PermissionList returns permissions granted by role
+func (r *Role) PermissionList() []string {
+	if r.Permissions == "" {
+		return nil
+	}
+	return strings.Split(r.Permissions, ",")
+}
+
+// RoleNames returns names of roles assigned to user
+func (u *User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, r := range u.Roles {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// PermissionList returns distinct permissions granted by all user roles
+func (u *User) PermissionList() []string {
+	seen := map[string]bool{}
+	perms := []string{}
+	for _, r := range u.Roles {
+		for _, p := range r.PermissionList() {
+			if !seen[p] {
+				seen[p] = true
+				perms = append(perms, p)
+			}
+		}
+	}
+	return perms
+}
+
+// HasPermission reports whether permission is present in the list, taking wildcard into account
+func HasPermission(perms []string, perm string) bool {
+	for _, p := range perms {
+		if p == perm || p == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+type RoleRepository struct {
+	*gorm.RepositoryBase
+}
+
+func NewRoleRepository(c rdbms.DbContext) *RoleRepository {
+	repo := &RoleRepository{
+		RepositoryBase: &gorm.RepositoryBase{},
+	}
+
+	repo.SetContext(c)
+
+	return repo
+}
+
+func (rr *RoleRepository) FindByName(name string) (*Role, error) {
+	var role Role
+	if err := rr.First(&role, Role{Name: name}); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// EnsureRole creates role or updates its permissions when it already exists
+func (rr *RoleRepository) EnsureRole(name string, perms ...string) (*Role, error) {
+	role, err := rr.FindByName(name)
+	if err != nil {
+		role = &Role{Name: name}
+	}
+
+	role.Permissions = strings.Join(perms, ",")
+	if err := rr.Save(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// AssignRole grants role to user
+func (rr *RoleRepository) AssignRole(user *User, role *Role) error {
+	db := rr.Context().(*gorm.DbContext).DB
+	if err := db.Model(user).Association("Roles").Append(role).Error; err != nil {
+		return err
+	}
+	return nil
+}
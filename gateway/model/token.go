@@ -0,0 +1,179 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+)
+
+// ErrInvalidToken is returned when refresh token is unknown, expired or revoked
+var ErrInvalidToken = errors.New("Invalid or expired token")
+
+// RefreshToken is a persisted, single use token exchanged for new access tokens.
+// Only hash of the token is stored. Tokens rotated from the same login share Family,
+// so reuse of already rotated token revokes the whole chain.
+type RefreshToken struct {
+	ID        uint   `gorm:"primary_key"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"unique_index"`
+	Family    string `gorm:"index"`
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RevokedToken is an access token (identified by JWT ID) revoked before its expiry
+type RevokedToken struct {
+	ID        uint      `gorm:"primary_key"`
+	TokenID   string    `gorm:"unique_index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+type TokenRepository struct {
+	*gorm.RepositoryBase
+}
+
+func NewTokenRepository(c rdbms.DbContext) *TokenRepository {
+	repo := &TokenRepository{
+		RepositoryBase: &gorm.RepositoryBase{},
+	}
+
+	repo.SetContext(c)
+
+	return repo
+}
+
+// NewTokenID generates random identifier suitable for tokens and token families
+func NewTokenID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// IssueRefreshToken creates new refresh token for user. Empty family starts a new one.
+func (tr *TokenRepository) IssueRefreshToken(userID uint, family string, ttl time.Duration) (string, error) {
+	token, err := NewTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	if family == "" {
+		if family, err = NewTokenID(); err != nil {
+			return "", err
+		}
+	}
+
+	rt := &RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		Family:    family,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := tr.Save(rt); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken revokes given refresh token and issues its successor.
+// Presenting already revoked token is treated as theft and revokes the whole family.
+func (tr *TokenRepository) RotateRefreshToken(token string, ttl time.Duration) (string, *RefreshToken, error) {
+	var rt RefreshToken
+	if err := tr.First(&rt, RefreshToken{TokenHash: hashToken(token)}); err != nil {
+		return "", nil, ErrInvalidToken
+	}
+
+	db := tr.Context().(*gorm.DbContext).DB
+	now := time.Now()
+
+	if rt.RevokedAt != nil {
+		db.Model(&RefreshToken{}).Where("family = ? AND revoked_at IS NULL", rt.Family).Update("revoked_at", now)
+
+		return "", nil, ErrInvalidToken
+	}
+	if now.After(rt.ExpiresAt) {
+		return "", nil, ErrInvalidToken
+	}
+
+	// conditional update guarantees that concurrent refreshes rotate the token only once
+	res := db.Model(&RefreshToken{}).Where("id = ? AND revoked_at IS NULL", rt.ID).Update("revoked_at", now)
+	if res.Error != nil {
+		return "", nil, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return "", nil, ErrInvalidToken
+	}
+
+	next, err := tr.IssueRefreshToken(rt.UserID, rt.Family, ttl)
+	if err != nil {
+		return "", nil, err
+	}
+	return next, &rt, nil
+}
+
+// FindRefreshToken finds refresh token, returns ErrInvalidToken when token is unknown
+func (tr *TokenRepository) FindRefreshToken(token string) (*RefreshToken, error) {
+	var rt RefreshToken
+	if err := tr.First(&rt, RefreshToken{TokenHash: hashToken(token)}); err != nil {
+		if IsNotFound(err) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &rt, nil
+}
+
+// RevokeRefreshToken revokes refresh token and all tokens rotated from the same login
+func (tr *TokenRepository) RevokeRefreshToken(rt *RefreshToken) error {
+	db := tr.Context().(*gorm.DbContext).DB
+	return db.Model(&RefreshToken{}).Where("family = ? AND revoked_at IS NULL", rt.Family).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserTokens revokes all refresh tokens of user, e.g. after password change
+func (tr *TokenRepository) RevokeUserTokens(userID uint) error {
+	db := tr.Context().(*gorm.DbContext).DB
+	return db.Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAccessToken adds access token to revocation list until it expires
+func (tr *TokenRepository) RevokeAccessToken(tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return ErrInvalidToken
+	}
+
+	var rt RevokedToken
+	if err := tr.First(&rt, RevokedToken{TokenID: tokenID}); err == nil {
+		return nil
+	}
+	return tr.Save(&RevokedToken{TokenID: tokenID, ExpiresAt: expiresAt})
+}
+
+// RevokedAccessTokens returns revoked access tokens which have not expired yet
+// and removes the expired ones.
+func (tr *TokenRepository) RevokedAccessTokens() ([]RevokedToken, error) {
+	db := tr.Context().(*gorm.DbContext).DB
+	now := time.Now()
+
+	if err := db.Where("expires_at < ?", now).Delete(&RevokedToken{}).Error; err != nil {
+		return nil, err
+	}
+
+	var tokens []RevokedToken
+	if err := db.Where("expires_at >= ?", now).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"database/sql"
+
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/data/access/rdbms/gorm"
+)
+
+// InTransaction runs function with database context of new transaction, so repositories created
+// with it share the transaction. Transaction is committed when function succeeds and rolled back otherwise.
+// Function given context of running transaction becomes part of that transaction.
+func InTransaction(c rdbms.DbContext, f func(tx rdbms.DbContext) error) error {
+	db := c.(*gorm.DbContext).DB
+	if _, ok := db.CommonDB().(*sql.Tx); ok {
+		return f(c)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer tx.Rollback()
+
+	if err := f(&gorm.DbContext{DB: tx}); err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// limits for user supplied values
+const (
+	MinLoginLength    = 3
+	MaxLoginLength    = 32
+	MinPasswordLength = 8
+	MaxPasswordLength = 72 // bcrypt ignores anything longer
+)
+
+var loginPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// ValidationError describes invalid value of single field
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is a list of field validation errors
+type ValidationErrors []ValidationError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(ve))
+	for _, e := range ve {
+		msgs = append(msgs, e.Field+": "+e.Message)
+	}
+	return "Validation failed: " + strings.Join(msgs, ", ")
+}
+
+// helper function to return nil interface when there are no errors
+func (ve ValidationErrors) orNil() error {
+	if len(ve) == 0 {
+		return nil
+	}
+	return ve
+}
+
+func validateLogin(field, login string) *ValidationError {
+	switch {
+	case len(login) < MinLoginLength || len(login) > MaxLoginLength:
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must be between %d and %d characters long", MinLoginLength, MaxLoginLength)}
+	case !loginPattern.MatchString(login):
+		return &ValidationError{Field: field, Message: "may contain only letters, digits, '_', '.' and '-'"}
+	}
+	return nil
+}
+
+func validatePassword(field, password string) *ValidationError {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("must be between %d and %d characters long", MinPasswordLength, MaxPasswordLength)}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/messages"
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	"github.com/gkarlik/quark-go-example/gateway/publisher"
+	"github.com/gkarlik/quark-go-example/gateway/resilience"
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gkarlik/quark-go/metrics"
+)
+
+// helper function to store message in outbox within transaction of domain changes it describes,
+// message is published by outbox relay once the transaction is committed
+func stageMessage(ctx context.Context, tx rdbms.DbContext, topic string, m messages.Message) error {
+	e, err := messages.Registered.Wrap(m)
+	if err != nil {
+		return err
+	}
+
+	// request of the gateway starts the conversation
+	e.CorrelationID = requestID(ctx)
+	if e.CorrelationID == "" {
+		e.CorrelationID = e.ID
+	}
+
+	data, err := e.Marshal()
+	if err != nil {
+		return err
+	}
+	return model.NewOutboxRepository(tx).Add(topic, data)
+}
+
+// time limit of publishing single outbox message, including broker confirmation
+const outboxPublishTimeout = 10 * time.Second
+
+// time batch of messages stays claimed by relay, messages of relay which stopped meanwhile
+// are published by other relay after the claim ends
+const outboxClaimDuration = time.Minute
+
+// outboxRelay publishes messages stored in outbox through the broker. Message is marked as sent only
+// after broker confirmed it accepted the message, so messages are delivered at least once - consumers
+// recognize duplicates by envelope ID.
+type outboxRelay struct {
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+	retry     resilience.RetryPolicy
+
+	publishedCounter metrics.Counter
+	failedCounter    metrics.Counter
+}
+
+// helper function to create outbox relay with settings loaded from environment variables
+func newOutboxRelay() *outboxRelay {
+	interval, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_OUTBOX_INTERVAL"))
+	if err != nil || interval <= 0 {
+		panic("Incorrect outbox interval value!")
+	}
+	batchSize, err := strconv.Atoi(quark.GetEnvVar("GATEWAY_OUTBOX_BATCH_SIZE"))
+	if err != nil || batchSize < 1 {
+		panic("Incorrect outbox batch size value!")
+	}
+	retention, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_OUTBOX_RETENTION"))
+	if err != nil || retention <= 0 {
+		panic("Incorrect outbox retention value!")
+	}
+	baseDelay, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_OUTBOX_RETRY_DELAY"))
+	if err != nil {
+		panic("Incorrect outbox retry delay value!")
+	}
+	maxDelay, err := time.ParseDuration(quark.GetEnvVar("GATEWAY_OUTBOX_MAX_RETRY_DELAY"))
+	if err != nil {
+		panic("Incorrect outbox max retry delay value!")
+	}
+
+	return &outboxRelay{
+		interval:         interval,
+		batchSize:        batchSize,
+		retention:        retention,
+		retry:            resilience.RetryPolicy{BaseDelay: baseDelay, MaxDelay: maxDelay},
+		publishedCounter: srv.Metrics().CreateCounter("outbox_published", "Number of outbox messages published"),
+		failedCounter:    srv.Metrics().CreateCounter("outbox_failed", "Number of failed attempts of publishing outbox messages"),
+	}
+}
+
+// function to publish pending messages until gateway shuts down
+func (o *outboxRelay) run() {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		// full batch means more messages are probably waiting
+		for !shuttingDown() {
+			published, err := o.relay()
+			if err != nil {
+				srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot relay outbox messages")
+				break
+			}
+			if published < o.batchSize {
+				break
+			}
+		}
+
+		if err := model.NewOutboxRepository(srv.Database()).DeleteSent(time.Now().Add(-o.retention)); err != nil {
+			srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot remove sent outbox messages")
+		}
+
+		select {
+		case <-serviceCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// helper function to publish message and wait for broker confirmation. Publishing is not cancelled
+// on shutdown, so message is either sent or left pending.
+func (o *outboxRelay) publish(m *model.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxPublishTimeout)
+	defer cancel()
+
+	return srv.events.Publish(ctx, m.Topic, publisher.Message{ContentType: "application/json", Body: []byte(m.Payload)})
+}
+
+// function to publish single batch of pending messages, returns number of published messages. Messages
+// are claimed in short transaction and published outside of it, so no rows are locked while waiting for
+// the broker. Messages which could not be published before the claim ends are left to the next run.
+func (o *outboxRelay) relay() (int, error) {
+	repo := model.NewOutboxRepository(srv.Database())
+
+	now := time.Now()
+	claimEnd := now.Add(outboxClaimDuration)
+	pending, err := repo.ClaimPending(o.batchSize, now, claimEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	var published int
+	for i := range pending {
+		m := &pending[i]
+
+		// message published after the claim ends could be published by other relay as well
+		if time.Now().Add(outboxPublishTimeout).After(claimEnd) {
+			break
+		}
+
+		if err := o.publish(m); err != nil {
+			o.failedCounter.Inc()
+
+			srv.Log().WarnWithFields(logger.Fields{
+				"error":   err,
+				"id":      m.ID,
+				"topic":   m.Topic,
+				"attempt": m.Attempts + 1,
+			}, "Cannot publish outbox message")
+
+			// broker is most likely unavailable, remaining messages wait until the claim ends
+			return published, repo.MarkFailed(m, err, time.Now().Add(o.retry.Backoff(m.Attempts+1)))
+		}
+		o.publishedCounter.Inc()
+
+		if err := repo.MarkSent(m, time.Now()); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
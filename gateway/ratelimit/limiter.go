@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period of fixed rate limiting window
+type Period int
+
+// supported periods
+const (
+	Minute Period = iota
+	Day
+	Month
+)
+
+// Window returns bounds of the period window containing given time. Days and months are counted in UTC.
+func (p Period) Window(now time.Time) (start, end time.Time) {
+	now = now.UTC()
+
+	switch p {
+	case Minute:
+		start = now.Truncate(time.Minute)
+		return start, start.Add(time.Minute)
+	case Day:
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	default:
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+// Limit is a maximal number of requests in period
+type Limit struct {
+	Period Period
+	Max    int
+}
+
+// Result of rate limit check. Limit, Remaining and Reset describe the most restrictive window -
+// the one which rejected request or the one with fewest requests remaining.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Window is a request counter of single client in single period
+type Window struct {
+	ID  string
+	Max int
+	End time.Time
+}
+
+// Store keeps request counters. Stores shared by gateway instances make limits hold cluster-wide.
+type Store interface {
+	// Take atomically counts request in all windows unless one of them is exhausted
+	// and returns current counts of the windows.
+	Take(windows []Window, now time.Time) (counts []int, allowed bool, err error)
+	// Peek returns current counts of the windows without counting request
+	Peek(windows []Window, now time.Time) (counts []int, err error)
+}
+
+// Limiter counts requests of clients in fixed windows kept in store
+type Limiter struct {
+	store Store
+}
+
+// NewLimiter creates limiter using given store
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Allow counts request of client identified by key when it fits in all limits. Rejected requests
+// are not counted. Without limits request is allowed and Remaining is negative.
+func (l *Limiter) Allow(key string, limits []Limit, now time.Time) (Result, error) {
+	if len(limits) == 0 {
+		return Result{Allowed: true, Remaining: -1}, nil
+	}
+
+	windows := limitWindows(key, limits, now)
+	counts, allowed, err := l.store.Take(windows, now)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(windows, counts, allowed), nil
+}
+
+// Status reports limits of client identified by key without counting request. Allowed tells
+// whether next request would fit in all limits.
+func (l *Limiter) Status(key string, limits []Limit, now time.Time) (Result, error) {
+	if len(limits) == 0 {
+		return Result{Allowed: true, Remaining: -1}, nil
+	}
+
+	windows := limitWindows(key, limits, now)
+	counts, err := l.store.Peek(windows, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed := true
+	for i, w := range windows {
+		if counts[i] >= w.Max {
+			allowed = false
+		}
+	}
+	return result(windows, counts, allowed), nil
+}
+
+// helper function to get counter windows of the client containing given time
+func limitWindows(key string, limits []Limit, now time.Time) []Window {
+	windows := make([]Window, len(limits))
+	for i, limit := range limits {
+		start, end := limit.Period.Window(now)
+		windows[i] = Window{
+			ID:  fmt.Sprintf("%s|%d|%d", key, limit.Period, start.Unix()),
+			Max: limit.Max,
+			End: end,
+		}
+	}
+	return windows
+}
+
+// helper function to describe the most restrictive window
+func result(windows []Window, counts []int, allowed bool) Result {
+	res := Result{Allowed: allowed, Remaining: -1}
+	for i, w := range windows {
+		remaining := w.Max - counts[i]
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		// rejected request reports the exhausted window
+		if !allowed && remaining > 0 {
+			continue
+		}
+		if res.Remaining < 0 || remaining < res.Remaining {
+			res.Limit = w.Max
+			res.Remaining = remaining
+			res.Reset = w.End
+		}
+	}
+	return res
+}
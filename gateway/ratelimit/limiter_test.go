@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+var now = time.Date(2024, time.March, 31, 23, 59, 30, 0, time.UTC)
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		period     Period
+		start, end time.Time
+	}{
+		{Minute, time.Date(2024, time.March, 31, 23, 59, 0, 0, time.UTC), time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{Day, time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{Month, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		start, end := tt.period.Window(now)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("Period %d: expected [%s, %s), got [%s, %s)", tt.period, tt.start, tt.end, start, end)
+		}
+	}
+}
+
+func TestAllowWithoutLimits(t *testing.T) {
+	res, err := NewLimiter(NewMemoryStore()).Allow("alice", nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed || res.Remaining != -1 {
+		t.Errorf("Expected unlimited request, got %+v", res)
+	}
+}
+
+func TestAllowReportsMostRestrictiveWindow(t *testing.T) {
+	l := NewLimiter(NewMemoryStore())
+	limits := []Limit{{Period: Minute, Max: 2}, {Period: Day, Max: 10}}
+
+	for i := 1; i <= 2; i++ {
+		res, err := l.Allow("alice", limits, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Allowed || res.Limit != 2 || res.Remaining != 2-i {
+			t.Errorf("Request %d: unexpected result %+v", i, res)
+		}
+	}
+
+	res, _ := l.Allow("alice", limits, now)
+	if res.Allowed || res.Limit != 2 || res.Remaining != 0 {
+		t.Errorf("Expected request rejected by minute limit, got %+v", res)
+	}
+	if _, end := Minute.Window(now); !res.Reset.Equal(end) {
+		t.Errorf("Expected reset at %s, got %s", end, res.Reset)
+	}
+
+	// rejected request is not counted in the daily window
+	res, _ = l.Status("alice", limits[1:], now)
+	if res.Limit != 10 || res.Remaining != 8 {
+		t.Errorf("Expected 2 requests counted in daily window, got %+v", res)
+	}
+
+	// other clients have own counters
+	if res, _ := l.Allow("bob", limits, now); !res.Allowed {
+		t.Error("Expected request of other client to be allowed")
+	}
+}
+
+func TestStatusDoesNotCountRequest(t *testing.T) {
+	l := NewLimiter(NewMemoryStore())
+	limits := []Limit{{Period: Minute, Max: 1}}
+
+	res, err := l.Status("alice", limits, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Allowed || res.Remaining != 1 {
+		t.Errorf("Expected fresh limit, got %+v", res)
+	}
+
+	l.Allow("alice", limits, now)
+	res, _ = l.Status("alice", limits, now)
+	if res.Allowed || res.Remaining != 0 {
+		t.Errorf("Expected exhausted limit, got %+v", res)
+	}
+	if res, _ := l.Allow("alice", limits, now); res.Allowed {
+		t.Error("Expected request to be rejected")
+	}
+}
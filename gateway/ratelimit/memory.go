@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// how often expired windows are removed
+const sweepInterval = time.Minute
+
+type counter struct {
+	count int
+	end   time.Time
+}
+
+// MemoryStore keeps counters in memory of single gateway instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: map[string]*counter{},
+	}
+}
+
+// Take counts request in all windows unless one of them is exhausted
+func (s *MemoryStore) Take(windows []Window, now time.Time) ([]int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	allowed := true
+	counters := make([]*counter, len(windows))
+	for i, w := range windows {
+		c, ok := s.counters[w.ID]
+		if !ok {
+			c = &counter{end: w.End}
+			s.counters[w.ID] = c
+		}
+		counters[i] = c
+
+		if c.count >= w.Max {
+			allowed = false
+		}
+	}
+
+	counts := make([]int, len(windows))
+	for i, c := range counters {
+		if allowed {
+			c.count++
+		}
+		counts[i] = c.count
+	}
+	return counts, allowed, nil
+}
+
+// Peek returns current counts of the windows
+func (s *MemoryStore) Peek(windows []Window, now time.Time) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make([]int, len(windows))
+	for i, w := range windows {
+		if c, ok := s.counters[w.ID]; ok && c.end.After(now) {
+			counts[i] = c.count
+		}
+	}
+	return counts, nil
+}
+
+// must be called with lock held
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for id, c := range s.counters {
+		if !c.end.After(now) {
+			delete(s.counters, id)
+		}
+	}
+}
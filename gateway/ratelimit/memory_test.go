@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	s := NewMemoryStore()
+	windows := []Window{{ID: "a", Max: 1, End: now.Add(time.Minute)}, {ID: "b", Max: 5, End: now.Add(time.Hour)}}
+
+	counts, allowed, _ := s.Take(windows, now)
+	if !allowed || counts[0] != 1 || counts[1] != 1 {
+		t.Errorf("Expected request counted in both windows, got %v %v", counts, allowed)
+	}
+
+	counts, allowed, _ = s.Take(windows, now)
+	if allowed || counts[0] != 1 || counts[1] != 1 {
+		t.Errorf("Expected rejected request not to be counted, got %v %v", counts, allowed)
+	}
+}
+
+func TestMemoryStoreExpiredWindows(t *testing.T) {
+	s := NewMemoryStore()
+	windows := []Window{{ID: "a", Max: 5, End: now.Add(time.Minute)}}
+
+	s.Take(windows, now)
+	if counts, _ := s.Peek(windows, now); counts[0] != 1 {
+		t.Errorf("Expected count 1, got %d", counts[0])
+	}
+
+	later := now.Add(2 * time.Minute)
+	if counts, _ := s.Peek(windows, later); counts[0] != 0 {
+		t.Errorf("Expected expired window not to be reported, got %d", counts[0])
+	}
+
+	s.Take(nil, later)
+	if len(s.counters) != 0 {
+		t.Errorf("Expected expired window to be removed, got %d counters", len(s.counters))
+	}
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	jinzhu "github.com/jinzhu/gorm"
+)
+
+// PostgresStore keeps counters in rate_limit_counter table shared by all gateway instances
+type PostgresStore struct {
+	db *jinzhu.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresStore creates store using given database connection pool
+func NewPostgresStore(db *jinzhu.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take counts request in all windows unless one of them is exhausted. Counter rows are locked
+// in order of their identifiers, so concurrent requests of the same client cannot deadlock.
+func (s *PostgresStore) Take(windows []Window, now time.Time) ([]int, bool, error) {
+	if err := s.sweep(now); err != nil {
+		return nil, false, err
+	}
+
+	order := make([]int, len(windows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return windows[order[a]].ID < windows[order[b]].ID
+	})
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, false, tx.Error
+	}
+
+	allowed := true
+	counts := make([]int, len(windows))
+	for _, i := range order {
+		w := windows[i]
+
+		err := tx.Exec(`INSERT INTO rate_limit_counter (id, count, expires_at) VALUES (?, 0, ?) ON CONFLICT (id) DO NOTHING`, w.ID, w.End).Error
+		if err == nil {
+			err = tx.Raw(`SELECT count FROM rate_limit_counter WHERE id = ? FOR UPDATE`, w.ID).Row().Scan(&counts[i])
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, false, err
+		}
+
+		if counts[i] >= w.Max {
+			allowed = false
+		}
+	}
+
+	if allowed {
+		for i, w := range windows {
+			if err := tx.Exec(`UPDATE rate_limit_counter SET count = count + 1 WHERE id = ?`, w.ID).Error; err != nil {
+				tx.Rollback()
+				return nil, false, err
+			}
+			counts[i]++
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, false, err
+	}
+	return counts, allowed, nil
+}
+
+// Peek returns current counts of the windows, counters which do not exist yet are zero
+func (s *PostgresStore) Peek(windows []Window, now time.Time) ([]int, error) {
+	ids := make([]string, len(windows))
+	for i, w := range windows {
+		ids[i] = w.ID
+	}
+
+	rows, err := s.db.Raw(`SELECT id, count FROM rate_limit_counter WHERE id IN (?) AND expires_at > ?`, ids, now).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := map[string]int{}
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		found[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make([]int, len(windows))
+	for i, w := range windows {
+		counts[i] = found[w.ID]
+	}
+	return counts, nil
+}
+
+// helper function to periodically remove expired counters
+func (s *PostgresStore) sweep(now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	return s.db.Exec(`DELETE FROM rate_limit_counter WHERE expires_at < ?`, now).Error
+}
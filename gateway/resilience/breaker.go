@@ -0,0 +1,151 @@
+package resilience
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State of circuit breaker
+type State int
+
+// circuit breaker states
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// OpenError is returned when call is rejected because circuit of the backend is open
+type OpenError struct {
+	Backend    string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("Circuit breaker for %s is open, retry after %s", e.Backend, e.RetryAfter)
+}
+
+// BreakerPolicy configures circuit breakers
+type BreakerPolicy struct {
+	// number of consecutive failures opening the circuit
+	FailureThreshold int
+	// time circuit stays open before trial call is let through
+	OpenTimeout time.Duration
+}
+
+// StateChangeFunc is notified about circuit breaker state transitions
+type StateChangeFunc func(backend string, from, to State)
+
+// Breaker is a circuit breaker guarding calls to single backend. After FailureThreshold
+// consecutive failures it opens and rejects calls for OpenTimeout, then lets single trial
+// call through (half-open) which either closes or opens it again.
+//
+// Every state change starts new generation. Results of calls allowed in previous generations,
+// e.g. finishing late while breaker is half-open, tell nothing about current state and are ignored.
+type Breaker struct {
+	backend  string
+	policy   BreakerPolicy
+	onChange StateChangeFunc
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool
+	// incremented on every state change
+	generation uint64
+}
+
+// Allow reports whether call may proceed and returns generation its result belongs to.
+// Returns *OpenError when call is rejected.
+func (b *Breaker) Allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		elapsed := time.Since(b.openedAt)
+		if elapsed < b.policy.OpenTimeout {
+			return 0, &OpenError{Backend: b.backend, RetryAfter: b.policy.OpenTimeout - elapsed}
+		}
+		b.setState(HalfOpen)
+		b.trial = true
+	case HalfOpen:
+		// only one trial call at a time
+		if b.trial {
+			return 0, &OpenError{Backend: b.backend, RetryAfter: time.Second}
+		}
+		b.trial = true
+	}
+	return b.generation, nil
+}
+
+// Record reports result of call allowed in given generation
+func (b *Breaker) Record(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+	b.trial = false
+
+	if success {
+		b.failures = 0
+		if b.state != Closed {
+			b.setState(Closed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == HalfOpen || b.failures >= b.policy.FailureThreshold {
+		b.openedAt = time.Now()
+		if b.state != Open {
+			b.setState(Open)
+		}
+	}
+}
+
+// Release reports that allowed call finished without result, e.g. was cancelled by caller,
+// so it tells nothing about health of the backend
+func (b *Breaker) Release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation {
+		b.trial = false
+	}
+}
+
+// State returns current state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// must be called with lock held
+func (b *Breaker) setState(s State) {
+	from := b.state
+	b.state = s
+	b.generation++
+
+	if b.onChange != nil {
+		b.onChange(b.backend, from, s)
+	}
+}
@@ -0,0 +1,117 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+)
+
+// helper function to open breaker with threshold of single failure and let its open timeout pass
+func openedBreaker(t *testing.T) *Breaker {
+	e := NewExecutor(CircuitBreaker(BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Millisecond}))
+	b := e.Breaker("sum")
+
+	generation, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Record(generation, false)
+	time.Sleep(2 * time.Millisecond)
+
+	return b
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	var transitions []State
+	e := NewExecutor(
+		CircuitBreaker(BreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Hour}),
+		OnStateChange(func(backend string, from, to State) { transitions = append(transitions, to) }),
+	)
+	b := e.Breaker("sum")
+
+	for i := 0; i < 2; i++ {
+		generation, err := b.Allow()
+		if err != nil {
+			t.Fatalf("Call %d rejected: %v", i+1, err)
+		}
+		b.Record(generation, false)
+	}
+
+	if b.State() != Open {
+		t.Fatalf("Expected open breaker, got %s", b.State())
+	}
+	if _, err := b.Allow(); err == nil {
+		t.Error("Expected call to be rejected by open breaker")
+	} else if _, ok := err.(*OpenError); !ok {
+		t.Errorf("Expected open error, got %v", err)
+	}
+	if len(transitions) != 1 || transitions[0] != Open {
+		t.Errorf("Expected single transition to open, got %v", transitions)
+	}
+}
+
+func TestBreakerHalfOpenTrial(t *testing.T) {
+	b := openedBreaker(t)
+
+	generation, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected trial call, got %v", err)
+	}
+	if b.State() != HalfOpen {
+		t.Fatalf("Expected half-open breaker, got %s", b.State())
+	}
+	if _, err := b.Allow(); err == nil {
+		t.Error("Expected only one trial call")
+	}
+
+	b.Record(generation, true)
+	if b.State() != Closed {
+		t.Errorf("Expected closed breaker after successful trial, got %s", b.State())
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	e := NewExecutor(CircuitBreaker(BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Millisecond}))
+	b := e.Breaker("sum")
+
+	// slow call allowed while breaker is closed
+	stale, _ := b.Allow()
+	failed, _ := b.Allow()
+	b.Record(failed, false)
+	time.Sleep(2 * time.Millisecond)
+
+	trial, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected trial call, got %v", err)
+	}
+
+	b.Record(stale, true)
+	if b.State() != HalfOpen {
+		t.Fatalf("Expected stale result to be ignored, got %s breaker", b.State())
+	}
+	if _, err := b.Allow(); err == nil {
+		t.Error("Expected stale result not to let second trial call through")
+	}
+	b.Release(stale)
+	if _, err := b.Allow(); err == nil {
+		t.Error("Expected stale release not to let second trial call through")
+	}
+
+	b.Record(trial, false)
+	if b.State() != Open {
+		t.Errorf("Expected open breaker after failed trial, got %s", b.State())
+	}
+}
+
+func TestBreakerReleaseKeepsState(t *testing.T) {
+	b := openedBreaker(t)
+
+	generation, _ := b.Allow()
+	b.Release(generation)
+
+	if b.State() != HalfOpen {
+		t.Errorf("Expected half-open breaker, got %s", b.State())
+	}
+	if _, err := b.Allow(); err != nil {
+		t.Errorf("Expected another trial after release, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures retries with exponential backoff and full jitter
+type RetryPolicy struct {
+	// total number of attempts including the first one
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Backoff returns randomized delay before given retry (counted from 1)
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	d := p.BaseDelay << uint(retry-1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// Executor runs calls to backends guarded by per-backend circuit breakers, retrying idempotent calls
+type Executor struct {
+	retry     RetryPolicy
+	breaker   BreakerPolicy
+	onChange  StateChangeFunc
+	transient func(error) bool
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// Option configures Executor
+type Option func(*Executor)
+
+// Retry sets retry policy
+func Retry(p RetryPolicy) Option {
+	return func(e *Executor) {
+		e.retry = p
+	}
+}
+
+// CircuitBreaker sets circuit breaker policy
+func CircuitBreaker(p BreakerPolicy) Option {
+	return func(e *Executor) {
+		e.breaker = p
+	}
+}
+
+// OnStateChange sets function notified about circuit breaker transitions
+func OnStateChange(f StateChangeFunc) Option {
+	return func(e *Executor) {
+		e.onChange = f
+	}
+}
+
+// Transient sets function classifying errors as transient backend failures.
+// Only transient errors are retried and counted by circuit breakers - other errors
+// (e.g. invalid argument) mean backend is healthy. By default all errors are transient.
+func Transient(f func(error) bool) Option {
+	return func(e *Executor) {
+		e.transient = f
+	}
+}
+
+// NewExecutor creates executor with given options
+func NewExecutor(opts ...Option) *Executor {
+	e := &Executor{
+		retry:     RetryPolicy{MaxAttempts: 1},
+		breaker:   BreakerPolicy{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+		transient: func(error) bool { return true },
+		breakers:  map[string]*Breaker{},
+	}
+
+	for _, o := range opts {
+		o(e)
+	}
+	return e
+}
+
+// Breaker returns circuit breaker of the backend
+func (e *Executor) Breaker(backend string) *Breaker {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.breakers[backend]
+	if !ok {
+		b = &Breaker{backend: backend, policy: e.breaker, onChange: e.onChange}
+		e.breakers[backend] = b
+	}
+	return b
+}
+
+// Do resolves backend and executes call against it. Idempotent calls failing with transient
+// error are retried, possibly against other backend returned by resolve. When every attempt
+// is rejected by open circuit *OpenError is returned.
+func (e *Executor) Do(ctx context.Context, idempotent bool, resolve func() (string, error), call func(backend string) error) error {
+	attempts := 1
+	if idempotent && e.retry.MaxAttempts > 1 {
+		attempts = e.retry.MaxAttempts
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(e.retry.Backoff(attempt - 1)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		var backend string
+		if backend, err = resolve(); err != nil {
+			continue
+		}
+
+		b := e.Breaker(backend)
+		var generation uint64
+		if generation, err = b.Allow(); err != nil {
+			continue
+		}
+
+		err = call(backend)
+		if ctx.Err() == context.Canceled {
+			// caller is gone - result says nothing about the backend
+			b.Release(generation)
+			return err
+		}
+		if err == nil || !e.transient(err) {
+			b.Record(generation, true)
+			return err
+		}
+		b.Record(generation, false)
+
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
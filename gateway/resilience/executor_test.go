@@ -0,0 +1,73 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBackend = errors.New("backend failure")
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for retry := 1; retry <= 10; retry++ {
+		max := p.BaseDelay << uint(retry-1)
+		if max > p.MaxDelay {
+			max = p.MaxDelay
+		}
+		if d := p.Backoff(retry); d < 0 || d >= max {
+			t.Errorf("Retry %d: expected delay in [0, %s), got %s", retry, max, d)
+		}
+	}
+	if d := (RetryPolicy{}).Backoff(1); d != 0 {
+		t.Errorf("Expected no delay without policy, got %s", d)
+	}
+}
+
+func TestDoRetriesIdempotentCalls(t *testing.T) {
+	e := NewExecutor(Retry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+	resolve := func() (string, error) { return "sum", nil }
+
+	calls := 0
+	err := e.Do(context.Background(), true, resolve, func(string) error {
+		if calls++; calls < 3 {
+			return errBackend
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success after 3 calls, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = e.Do(context.Background(), false, resolve, func(string) error {
+		calls++
+		return errBackend
+	})
+	if err != errBackend || calls != 1 {
+		t.Errorf("Expected single call of non-idempotent request, got %v after %d", err, calls)
+	}
+}
+
+func TestDoDoesNotRetryPermanentErrors(t *testing.T) {
+	errInvalid := errors.New("invalid argument")
+	e := NewExecutor(
+		Retry(RetryPolicy{MaxAttempts: 3}),
+		CircuitBreaker(BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Hour}),
+		Transient(func(err error) bool { return err != errInvalid }),
+	)
+
+	calls := 0
+	err := e.Do(context.Background(), true, func() (string, error) { return "sum", nil }, func(string) error {
+		calls++
+		return errInvalid
+	})
+	if err != errInvalid || calls != 1 {
+		t.Errorf("Expected single call, got %v after %d", err, calls)
+	}
+	if s := e.Breaker("sum").State(); s != Closed {
+		t.Errorf("Expected closed breaker, got %s", s)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	"github.com/gkarlik/quark-go/logger"
+)
+
+// header identifying request in responses, logs and calls to backend services
+const requestIDHeader = "X-Request-ID"
+
+// media types offered by API endpoints
+const (
+	jsonMediaType = "application/json"
+	textMediaType = "text/plain"
+)
+
+// error codes of the error envelope
+const (
+	codeBadRequest       = "bad_request"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeNotAcceptable    = "not_acceptable"
+	codeConflict         = "conflict"
+	codeValidationFailed = "validation_failed"
+	codeRateLimited      = "rate_limited"
+	codeInternal         = "internal_error"
+	codeUpstreamError    = "upstream_error"
+	codeUpstreamTimeout  = "upstream_timeout"
+	codeCircuitOpen      = "circuit_open"
+)
+
+// error codes used when handler does not give more specific one
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          codeBadRequest,
+	http.StatusUnauthorized:        codeUnauthorized,
+	http.StatusForbidden:           codeForbidden,
+	http.StatusNotFound:            codeNotFound,
+	http.StatusMethodNotAllowed:    codeMethodNotAllowed,
+	http.StatusNotAcceptable:       codeNotAcceptable,
+	http.StatusConflict:            codeConflict,
+	http.StatusUnprocessableEntity: codeValidationFailed,
+	http.StatusTooManyRequests:     codeRateLimited,
+	http.StatusGatewayTimeout:      codeUpstreamTimeout,
+	http.StatusServiceUnavailable:  codeCircuitOpen,
+}
+
+// errorResponse describes failed request. Service is set when failure comes from backend service.
+type errorResponse struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Service   string                 `json:"service,omitempty"`
+	Errors    model.ValidationErrors `json:"errors,omitempty"`
+}
+
+// error envelope returned by all gateway endpoints
+type errorEnvelope struct {
+	Error errorResponse `json:"error"`
+}
+
+type requestIDContextKey struct{}
+
+// middleware assigning identifier to every request. Identifier given by client is preserved.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			var err error
+			if id, err = model.NewTokenID(); err != nil {
+				srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot generate request ID")
+			}
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id)))
+	})
+}
+
+// helper function to get identifier of the request
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// helper function to write JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", jsonMediaType)
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot encode response")
+	}
+}
+
+// helper function to write error envelope. Missing code is derived from status
+// and request ID is taken from response headers.
+func writeErrorResponse(w http.ResponseWriter, status int, e errorResponse) {
+	if e.Code == "" {
+		if e.Code = statusCodes[status]; e.Code == "" {
+			e.Code = codeInternal
+		}
+	}
+	e.RequestID = w.Header().Get(requestIDHeader)
+
+	writeJSON(w, status, errorEnvelope{Error: e})
+}
+
+// helper function to write JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeErrorResponse(w, status, errorResponse{Message: message})
+}
+
+// limit of plain text error message kept by envelopeWriter
+const maxErrorMessageLength = 1024
+
+// envelopeWriter holds back error responses which are not JSON, e.g. written by http.Error,
+// so they can be replaced with error envelope
+type envelopeWriter struct {
+	http.ResponseWriter
+	status  int
+	message bytes.Buffer
+}
+
+func (w *envelopeWriter) WriteHeader(status int) {
+	if status >= http.StatusBadRequest && !strings.HasPrefix(w.Header().Get("Content-Type"), jsonMediaType) {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *envelopeWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		return w.ResponseWriter.Write(b)
+	}
+	if n := maxErrorMessageLength - w.message.Len(); n > 0 {
+		if len(b) < n {
+			n = len(b)
+		}
+		w.message.Write(b[:n])
+	}
+	return len(b), nil
+}
+
+// helper function to write held back error response as error envelope
+func (w *envelopeWriter) flush() {
+	if w.status == 0 {
+		return
+	}
+
+	message := strings.TrimSpace(w.message.String())
+	if message == "" {
+		message = http.StatusText(w.status)
+	}
+	w.Header().Del("X-Content-Type-Options")
+	writeError(w.ResponseWriter, w.status, message)
+}
+
+// middleware replacing plain text error responses, e.g. of third party handlers, with error envelope
+func withErrorEnvelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ew := &envelopeWriter{ResponseWriter: w}
+		defer ew.flush()
+
+		next.ServeHTTP(ew, r)
+	})
+}
+
+// helper function to serve request with response writer which is not wrapped by withErrorEnvelope,
+// so handlers behind third party middleware can stream responses and hijack connections
+func bypassErrorEnvelope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ew, ok := w.(*envelopeWriter); ok {
+			w = ew.ResponseWriter
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// function to handle requests not matching any route
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "Resource not found")
+}
+
+// function to handle requests matching route path but not its method
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s is not allowed", r.Method))
+}
+
+// helper function to choose media type from offers according to request Accept header.
+// Returns empty string when none of offers is acceptable.
+func negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q := 0.0
+		for _, part := range strings.Split(accept, ",") {
+			params := strings.Split(part, ";")
+
+			mediaRange := strings.TrimSpace(params[0])
+			if !matchMediaRange(mediaRange, offer) {
+				continue
+			}
+
+			rangeQ := 1.0
+			for _, p := range params[1:] {
+				p = strings.TrimSpace(p)
+				if strings.HasPrefix(p, "q=") {
+					if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+						rangeQ = v
+					}
+				}
+			}
+			if rangeQ > q {
+				q = rangeQ
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// helper function to check whether media type matches media range, e.g. text/* or */*
+func matchMediaRange(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
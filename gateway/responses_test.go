@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// helper function to decode error envelope of the response
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorResponse {
+	if ct := w.Header().Get("Content-Type"); ct != jsonMediaType {
+		t.Fatalf("Expected %s content type, got '%s': %s", jsonMediaType, ct, w.Body.String())
+	}
+
+	var e errorEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	return e.Error
+}
+
+func TestErrorEnvelopeReplacesPlainTextErrors(t *testing.T) {
+	h := withErrorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "token is expired", http.StatusUnauthorized)
+	}))
+
+	w := httptest.NewRecorder()
+	w.Header().Set(requestIDHeader, "42")
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	e := decodeError(t, w)
+	if e.Code != codeUnauthorized || e.Message != "token is expired" || e.RequestID != "42" {
+		t.Errorf("Unexpected error %+v", e)
+	}
+}
+
+func TestErrorEnvelopeKeepsOtherResponses(t *testing.T) {
+	h := withErrorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/forbidden" {
+			writeError(w, http.StatusForbidden, "Permission 'sum' is required")
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("Expected successful response, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/forbidden", nil))
+	if e := decodeError(t, w); w.Code != http.StatusForbidden || e.Message != "Permission 'sum' is required" {
+		t.Errorf("Expected error to be written once, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBypassErrorEnvelope(t *testing.T) {
+	var inner http.ResponseWriter
+	h := withErrorEnvelope(bypassErrorEnvelope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = w
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+	if inner != w {
+		t.Errorf("Expected handler to get original response writer, got %T", inner)
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	r.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodPost)
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound, codeNotFound},
+		{http.MethodGet, "/logout", http.StatusMethodNotAllowed, codeMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.status, w.Code)
+			continue
+		}
+		if e := decodeError(t, w); e.Code != tt.code {
+			t.Errorf("%s %s: expected code %s, got %s", tt.method, tt.path, tt.code, e.Code)
+		}
+	}
+}
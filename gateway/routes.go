@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/gateway/client"
+	"github.com/gkarlik/quark-go-example/gateway/resilience"
+	"github.com/gkarlik/quark-go-example/gateway/routing"
+	"github.com/gkarlik/quark-go/service/trace"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+)
+
+// routeHandler proxies requests of single configured route to its backend service
+type routeHandler struct {
+	route    routing.Route
+	executor *resilience.Executor
+
+	// routes describing operation only
+	format *template.Template
+
+	// gRPC routes only
+	pool     *client.Pool
+	request  reflect.Type
+	response reflect.Type
+}
+
+// operationResponse is a JSON document describing result of API operation
+type operationResponse struct {
+	Operation string        `json:"operation"`
+	Operands  []interface{} `json:"operands"`
+	Result    interface{}   `json:"result"`
+}
+
+// data available to response format template
+type formatData struct {
+	Vars   map[string]string
+	Result map[string]interface{}
+}
+
+// helper function to register configured routes in router. Middlewares are applied in order:
+// timeout, authentication with revocation check, rate limiting and permission check.
+func registerRoutes(r *mux.Router, config *routing.Config, authenticate func(http.Handler) http.Handler) {
+	for _, route := range config.Routes {
+		var handler http.Handler = newRouteHandler(route)
+		if route.Permission != "" {
+			handler = requirePermission(route.Permission, handler)
+		}
+		if route.RateLimit {
+			handler = limitRequests(handler)
+		} else {
+			handler = reportRateLimit(handler)
+		}
+		if route.Authenticated() {
+			handler = authenticate(handler)
+		}
+		if route.Timeout > 0 {
+			handler = withTimeout(route.Timeout, handler)
+		}
+
+		rt := r.Handle(route.Path, handler)
+		if len(route.Methods) > 0 {
+			rt.Methods(route.Methods...)
+		}
+	}
+}
+
+// helper function to create handler of the route with connections to its backend service
+func newRouteHandler(route routing.Route) *routeHandler {
+	h := &routeHandler{
+		route:    route,
+		executor: srv.executor(route.Protocol, route.Service),
+		format:   responseFormat(route),
+	}
+
+	if route.Protocol == routing.GRPC {
+		h.pool = srv.pool(route.Service)
+		h.request = messageType(route.Request)
+		h.response = messageType(route.Response)
+	}
+	return h
+}
+
+// pool returns connection pool to instances of gRPC service, creating it when needed
+func (g *gateway) pool(service string) *client.Pool {
+	if p, ok := g.pools[service]; ok {
+		return p
+	}
+
+	p, err := client.NewPool(quark.GetEnvVar("DISCOVERY"), service, g.Log(), grpc.WithInsecure())
+	if err != nil {
+		panic(fmt.Sprintf("Cannot create %s connection pool!", service))
+	}
+	g.pools[service] = p
+
+	return p
+}
+
+// executor returns executor guarding calls to backend service, creating it when needed.
+// Breakers are kept per service, so all callers of the same service share them.
+func (g *gateway) executor(protocol routing.Protocol, service string) *resilience.Executor {
+	key := string(protocol) + ":" + service
+	if e, ok := g.executors[key]; ok {
+		return e
+	}
+
+	var e *resilience.Executor
+	if protocol == routing.GRPC {
+		e = newExecutor(service, resilience.Transient(transientRPCError))
+	} else {
+		e = newExecutor(service, resilience.Transient(transientHTTPError))
+	}
+	g.executors[key] = e
+
+	return e
+}
+
+// helper function to find type of protobuf message registered by generated proxies
+func messageType(name string) reflect.Type {
+	t := proto.MessageType(name)
+	if t == nil {
+		panic(fmt.Sprintf("Unknown protobuf message '%s'!", name))
+	}
+	return t
+}
+
+// ServeHTTP handles request of the route
+func (h *routeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// report response time for monitoring purposes
+	start := time.Now()
+	defer func() {
+		responseTimeGauge.Set(float64(time.Since(start).Nanoseconds()))
+	}()
+
+	// handle request tracing span
+	span := srv.Tracer().StartSpan(h.route.Name + "_request")
+	defer span.Finish()
+
+	// HTTP backend response is passed through when route does not describe operation
+	if h.route.Operation == "" && h.route.Protocol == routing.HTTP {
+		data, ok := h.callHTTP(w, r, span, nil)
+		if ok {
+			w.WriteHeader(http.StatusOK)
+			w.Write(data)
+		}
+		return
+	}
+
+	// media type is negotiated before backend is called, so unacceptable request costs nothing
+	offers := h.offers()
+	mediaType := negotiate(r, offers...)
+	if mediaType == "" {
+		writeError(w, http.StatusNotAcceptable, fmt.Sprintf("Supported media types: %s", strings.Join(offers, ", ")))
+		return
+	}
+
+	var result map[string]interface{}
+	switch h.route.Protocol {
+	case routing.GRPC:
+		var ok bool
+		if result, ok = h.callGRPC(w, r, span); !ok {
+			return
+		}
+	case routing.HTTP:
+		data, ok := h.callHTTP(w, r, span, http.Header{"Accept": {jsonMediaType}})
+		if !ok {
+			return
+		}
+		var err error
+		if result, err = decodeResult(data); err != nil {
+			writeBackendError(w, r, h.route.Service, err)
+			return
+		}
+	}
+
+	h.writeResult(w, r, mediaType, result)
+}
+
+// function to handle call to HTTP service
+func (h *routeHandler) callHTTP(w http.ResponseWriter, r *http.Request, span trace.Span, header http.Header) ([]byte, bool) {
+	vars := mux.Vars(r)
+	path := h.route.ExpandTarget(func(name string) string {
+		return url.PathEscape(vars[name])
+	})
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	// body is buffered, so it can be sent again when call is retried
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Cannot read request body")
+		return nil, false
+	}
+
+	data, err := callHTTPBackend(r.Context(), h.executor, h.route.Service, h.route.Idempotent, h.route.Method, path, header, body, span)
+	if err != nil {
+		writeBackendError(w, r, h.route.Service, err)
+		return nil, false
+	}
+	return data, true
+}
+
+// function to handle call to RPC service. Request message is built from path variables.
+func (h *routeHandler) callGRPC(w http.ResponseWriter, r *http.Request, span trace.Span) (map[string]interface{}, bool) {
+	fields, err := json.Marshal(mux.Vars(r))
+	if err != nil {
+		writeBackendError(w, r, h.route.Service, err)
+		return nil, false
+	}
+
+	req := reflect.New(h.request.Elem()).Interface().(proto.Message)
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := u.Unmarshal(bytes.NewReader(fields), req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request parameters")
+		return nil, false
+	}
+
+	ctx := rpcContext(r.Context(), span)
+
+	resp := reflect.New(h.response.Elem()).Interface().(proto.Message)
+	err = invokeRPC(ctx, h.executor, h.pool, h.route.Idempotent, func(conn *grpc.ClientConn) error {
+		return grpc.Invoke(ctx, h.route.Method, req, resp, conn)
+	})
+	if err != nil {
+		writeBackendError(w, r, h.route.Service, err)
+		return nil, false
+	}
+
+	result, err := rpcResult(resp)
+	if err != nil {
+		writeBackendError(w, r, h.route.Service, err)
+		return nil, false
+	}
+	return result, true
+}
+
+// function to write backend result in negotiated media type
+func (h *routeHandler) writeResult(w http.ResponseWriter, r *http.Request, mediaType string, result map[string]interface{}) {
+	if h.route.Operation == "" {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	value, ok := result[h.route.Result]
+	if !ok {
+		writeBackendError(w, r, h.route.Service, fmt.Errorf("Response has no '%s' field", h.route.Result))
+		return
+	}
+
+	if mediaType == textMediaType {
+		var buf bytes.Buffer
+		if err := h.format.Execute(&buf, formatData{Vars: vars, Result: result}); err != nil {
+			writeBackendError(w, r, h.route.Service, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", textMediaType+"; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+		return
+	}
+
+	resp := operationResponse{
+		Operation: h.route.OperationName(vars),
+		Operands:  make([]interface{}, 0, len(h.route.Operands)),
+		Result:    jsonValue(value),
+	}
+	for _, name := range h.route.Operands {
+		resp.Operands = append(resp.Operands, jsonValue(vars[name]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// helper function to get media types of route responses, plain text is offered by routes with format
+func (h *routeHandler) offers() []string {
+	if h.format != nil {
+		return []string{jsonMediaType, textMediaType}
+	}
+	return []string{jsonMediaType}
+}
+
+// helper function to parse response format template of the route, missing fields of the result are errors
+func responseFormat(route routing.Route) *template.Template {
+	if route.Format == "" {
+		return nil
+	}
+	return template.Must(template.New(route.Name).Option("missingkey=error").Parse(route.Format))
+}
+
+// helper function to represent gRPC response as JSON object. Fields are named by their JSON names,
+// which are used by result and format of routes.
+func rpcResult(resp proto.Message) (map[string]interface{}, error) {
+	m := jsonpb.Marshaler{EmitDefaults: true}
+	data, err := m.MarshalToString(resp)
+	if err != nil {
+		return nil, err
+	}
+	return decodeResult([]byte(data))
+}
+
+// helper function to decode JSON object returned by backend, numbers are kept exact
+func decodeResult(data []byte) (map[string]interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var result map[string]interface{}
+	if err := d.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// helper function to represent numeric strings (e.g. int64 values encoded by jsonpb or decimals)
+// as JSON numbers. Numbers exceeding float64 range are kept exact as well.
+func jsonValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		_, err := strconv.ParseFloat(s, 64)
+		if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrRange {
+			err = nil
+		}
+		if err == nil && json.Valid([]byte(s)) {
+			return json.Number(s)
+		}
+	}
+	return v
+}
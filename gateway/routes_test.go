@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gkarlik/quark-go-example/gateway/proxies/sum"
+	"github.com/gkarlik/quark-go-example/gateway/resilience"
+	"github.com/gkarlik/quark-go-example/gateway/routing"
+	"github.com/gorilla/mux"
+)
+
+// helper function to find configured route
+func testRoute(t *testing.T, name string) routing.Route {
+	config, err := routing.Load("routes.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range config.Routes {
+		if route.Name == name {
+			return route
+		}
+	}
+	t.Fatalf("Route %s not found", name)
+	return routing.Route{}
+}
+
+// helper function to create gateway without backend connections, so only HTTP routes can be registered
+func testGateway(t *testing.T) {
+	prevSrv, prevChanged := srv, breakerChanged
+	srv = &gateway{executors: map[string]*resilience.Executor{}}
+	breakerChanged = func(string) resilience.StateChangeFunc { return nil }
+
+	t.Cleanup(func() { srv, breakerChanged = prevSrv, prevChanged })
+}
+
+// helper function to create handler of configured gRPC route without backend connections
+func testRouteHandler(t *testing.T, name string) *routeHandler {
+	route := testRoute(t, name)
+	return &routeHandler{route: route, format: responseFormat(route)}
+}
+
+func TestRegisteredMultiplyRouteOffersText(t *testing.T) {
+	testGateway(t)
+
+	route := testRoute(t, "mul_get")
+	r := mux.NewRouter()
+	registerRoutes(r, &routing.Config{Routes: []routing.Route{route}}, func(h http.Handler) http.Handler { return h })
+
+	var match mux.RouteMatch
+	if !r.Match(httptest.NewRequest(http.MethodGet, "/api/mul/2/3", nil), &match) {
+		t.Fatal("Expected route to be registered")
+	}
+
+	h := newRouteHandler(route)
+	req := httptest.NewRequest(http.MethodGet, "/api/mul/2/3", nil)
+	req.Header.Set("Accept", textMediaType)
+	if mediaType := negotiate(req, h.offers()...); mediaType != textMediaType {
+		t.Fatalf("Expected %s to be offered, got '%s'", textMediaType, mediaType)
+	}
+
+	w := httptest.NewRecorder()
+	h.writeResult(w, mux.SetURLVars(req, map[string]string{"a": "2", "b": "3"}), textMediaType, map[string]interface{}{"result": 6})
+	if body := strings.TrimSpace(w.Body.String()); body != "2 * 3 = 6" {
+		t.Errorf("Expected text '2 * 3 = 6', got '%s'", body)
+	}
+}
+
+func TestSumRouteRendersResult(t *testing.T) {
+	h := testRouteHandler(t, "sum_get")
+
+	result, err := rpcResult(&sum.SumResponse{Sum: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"a": "2", "b": "3"}
+
+	w := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/api/sum/2/3", nil), vars)
+	h.writeResult(w, r, textMediaType, result)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != "2 + 3 = 5" {
+		t.Errorf("Expected text '2 + 3 = 5', got '%s'", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.writeResult(w, r, jsonMediaType, result)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Result json.Number `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result != "5" {
+		t.Errorf("Expected result 5, got %s", resp.Result)
+	}
+}
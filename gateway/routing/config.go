@@ -0,0 +1,163 @@
+package routing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Protocol used to call backend service
+type Protocol string
+
+// supported protocols
+const (
+	HTTP Protocol = "http"
+	GRPC Protocol = "grpc"
+)
+
+// Route describes single operation exposed by the gateway and backend service handling it
+type Route struct {
+	// name used for request tracing spans
+	Name string `yaml:"name"`
+	// mux path template, e.g. /api/sum/{a:-?[0-9]+}/{b:-?[0-9]+}
+	Path string `yaml:"path"`
+	// allowed HTTP methods, all when empty
+	Methods []string `yaml:"methods"`
+
+	// name of backend service in service discovery catalog
+	Service  string   `yaml:"service"`
+	Protocol Protocol `yaml:"protocol"`
+	// HTTP method or full gRPC method name, e.g. /SumService/Sum
+	Method string `yaml:"method"`
+	// HTTP path of backend operation with {var} placeholders filled with path variables
+	Target string `yaml:"target"`
+	// names of registered protobuf messages of gRPC method
+	Request  string `yaml:"request"`
+	Response string `yaml:"response"`
+
+	// name of API operation - when set, response is a JSON document with operation,
+	// operands taken from path variables and result taken from backend response field.
+	// Name may be taken from path variable with {var} placeholder.
+	Operation string   `yaml:"operation"`
+	Operands  []string `yaml:"operands"`
+	Result    string   `yaml:"result"`
+	// optional text/template of text/plain representation, with .Vars and .Result available
+	Format string `yaml:"format"`
+
+	// whether authentication is required; permission implies it
+	Auth       bool   `yaml:"auth"`
+	Permission string `yaml:"permission"`
+	// whether requests are limited according to plan of the client
+	RateLimit bool `yaml:"rate_limit"`
+	// request processing time limit, no limit when zero
+	Timeout time.Duration `yaml:"timeout"`
+	// whether failed calls may be retried
+	Idempotent bool `yaml:"idempotent"`
+}
+
+// Authenticated reports whether route requires authenticated user
+func (r *Route) Authenticated() bool {
+	return r.Auth || r.Permission != ""
+}
+
+// Config is a list of routes exposed by the gateway
+type Config struct {
+	Routes []Route `yaml:"routes"`
+}
+
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// helper function to replace {var} placeholders with mapped values
+func expand(s string, mapping func(name string) string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(p string) string {
+		return mapping(p[1 : len(p)-1])
+	})
+}
+
+// ExpandTarget returns HTTP target with placeholders replaced by mapped values
+func (r *Route) ExpandTarget(mapping func(name string) string) string {
+	return expand(r.Target, mapping)
+}
+
+// OperationName returns name of API operation with placeholders replaced by path variables
+func (r *Route) OperationName(vars map[string]string) string {
+	return expand(r.Operation, func(name string) string {
+		return vars[name]
+	})
+}
+
+// Load reads routes from YAML or JSON file and validates them
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	// JSON is valid YAML, so both formats are handled by YAML parser
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("Cannot parse routes file %s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Validate checks that routes are complete
+func (c *Config) Validate() error {
+	if len(c.Routes) == 0 {
+		return fmt.Errorf("No routes defined")
+	}
+
+	names := map[string]bool{}
+	for i := range c.Routes {
+		r := &c.Routes[i]
+
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf("Route %d (%s): %s", i+1, r.Path, fmt.Sprintf(format, args...))
+		}
+
+		switch {
+		case r.Name == "":
+			return invalid("name is required")
+		case names[r.Name]:
+			return invalid("duplicated name '%s'", r.Name)
+		case r.Path == "":
+			return invalid("path is required")
+		case r.Service == "":
+			return invalid("service is required")
+		case r.Timeout < 0:
+			return invalid("timeout must not be negative")
+		}
+		names[r.Name] = true
+
+		if (r.Operation == "") != (r.Result == "") {
+			return invalid("operation and result must be given together")
+		}
+		if r.Format != "" && r.Operation == "" {
+			return invalid("format requires operation")
+		}
+
+		switch r.Protocol {
+		case HTTP:
+			if r.Target == "" {
+				return invalid("target is required for HTTP route")
+			}
+			if r.Method == "" {
+				r.Method = http.MethodGet
+			}
+		case GRPC:
+			if r.Method == "" || r.Request == "" || r.Response == "" {
+				return invalid("method, request and response are required for gRPC route")
+			}
+		default:
+			return invalid("unknown protocol '%s'", r.Protocol)
+		}
+	}
+	return nil
+}
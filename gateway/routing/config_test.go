@@ -0,0 +1,99 @@
+package routing
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func validRoute() Route {
+	return Route{
+		Name:     "multiply_get",
+		Path:     "/api/multiply/{a}/{b}",
+		Service:  "MultiplyService",
+		Protocol: HTTP,
+		Target:   "/multiply/{a}/{b}",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(r *Route)
+		err    string
+	}{
+		{"valid", func(r *Route) {}, ""},
+		{"no name", func(r *Route) { r.Name = "" }, "name is required"},
+		{"no path", func(r *Route) { r.Path = "" }, "path is required"},
+		{"no service", func(r *Route) { r.Service = "" }, "service is required"},
+		{"negative timeout", func(r *Route) { r.Timeout = -1 }, "timeout must not be negative"},
+		{"operation without result", func(r *Route) { r.Operation = "multiply" }, "operation and result must be given together"},
+		{"format without operation", func(r *Route) { r.Format = "{{.Result}}" }, "format requires operation"},
+		{"no target", func(r *Route) { r.Target = "" }, "target is required"},
+		{"incomplete gRPC", func(r *Route) { r.Protocol = GRPC; r.Method = "/SumService/Sum" }, "method, request and response are required"},
+		{"unknown protocol", func(r *Route) { r.Protocol = "ftp" }, "unknown protocol 'ftp'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := validRoute()
+			tt.change(&r)
+
+			err := (&Config{Routes: []Route{r}}).Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Unexpected error: %v", err)
+			case tt.err != "" && err == nil:
+				t.Errorf("Expected error '%s'", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Errorf("Expected error '%s', got '%v'", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	c := &Config{Routes: []Route{validRoute()}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Routes[0].Method != http.MethodGet {
+		t.Errorf("Expected default method %s, got '%s'", http.MethodGet, c.Routes[0].Method)
+	}
+}
+
+func TestValidateRejectsDuplicatedNames(t *testing.T) {
+	c := &Config{Routes: []Route{validRoute(), validRoute()}}
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "duplicated name") {
+		t.Errorf("Expected duplicated name error, got %v", err)
+	}
+	if err := (&Config{}).Validate(); err == nil {
+		t.Error("Expected error for empty config")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	r := validRoute()
+	r.Operation = "{op}"
+
+	target := r.ExpandTarget(func(name string) string { return map[string]string{"a": "2", "b": "3"}[name] })
+	if target != "/multiply/2/3" {
+		t.Errorf("Expected target /multiply/2/3, got %s", target)
+	}
+	if name := r.OperationName(map[string]string{"op": "sum"}); name != "sum" {
+		t.Errorf("Expected operation sum, got %s", name)
+	}
+}
+
+func TestLoadRoutesFile(t *testing.T) {
+	c, err := Load("../routes.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Routes) == 0 {
+		t.Error("Expected routes to be loaded")
+	}
+	if _, err := Load("missing.yaml"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
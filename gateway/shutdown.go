@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gkarlik/quark-go-example/gateway/shutdown"
+	"github.com/gkarlik/quark-go/logger"
+	sd "github.com/gkarlik/quark-go/service/discovery"
+)
+
+// serviceCtx is cancelled when gateway starts shutting down, so broker consumers and long-lived
+// streams can finish
+var serviceCtx, stopService = context.WithCancel(context.Background())
+
+// WebSocket connections are hijacked from HTTP server, so they are tracked separately
+var streams sync.WaitGroup
+
+// outbox relay finishes batch being published before database is closed
+var relays sync.WaitGroup
+
+// helper function to report whether gateway is shutting down
+func shuttingDown() bool {
+	return serviceCtx.Err() != nil
+}
+
+// function to shut down gateway gracefully. Gateway is removed from service discovery first, so no new
+// requests are routed to it, then in-flight requests and streams are given grace period to finish.
+func shutdownGracefully(server *http.Server, gracePeriod time.Duration) {
+	if err := srv.Discovery().DeregisterService(sd.WithInfo(srv.Info())); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot deregister service")
+	}
+	stopService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	// stop accepting connections and wait for in-flight requests
+	if err := server.Shutdown(ctx); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Requests not drained within grace period")
+		return
+	}
+	if err := shutdown.Wait(ctx, &streams); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Streams not drained within grace period")
+		return
+	}
+	if err := shutdown.Wait(ctx, &relays); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Outbox relay not stopped within grace period")
+		return
+	}
+	srv.Log().Info("Requests drained")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	proxy "github.com/gkarlik/quark-go-example/gateway/proxies/sum"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+)
+
+// limits of WebSocket connections
+const (
+	wsMaxMessageSize = 1024
+	wsWriteTimeout   = 5 * time.Second
+)
+
+// authentication relies on access token rather than cookies, so connections from any origin are allowed
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// numberMessage is a message sent by WebSocket client
+type numberMessage struct {
+	Value *int64 `json:"value"`
+}
+
+// sumMessage is a message sent to WebSocket client
+type sumMessage struct {
+	Sum int64 `json:"sum"`
+}
+
+// middleware passing access token given in access_token query parameter to authentication,
+// as browsers cannot set headers of WebSocket handshake
+func tokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if token := query.Get("access_token"); token != "" {
+			if r.Header.Get("Authorization") == "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			// token is removed from the URL, so it does not end up in logs and traces
+			query.Del("access_token")
+			r.URL.RawQuery = query.Encode()
+			r.RequestURI = r.URL.RequestURI()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// runningSumHandler bridges WebSocket clients onto RunningSum stream of SumService. Client sends
+// {"value":1} messages and receives {"sum":1} message after each of them.
+type runningSumHandler struct {
+	*calculator
+}
+
+// ServeHTTP handles WebSocket connection for the whole lifetime of the stream
+func (h *runningSumHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	span := srv.Tracer().StartSpan("running_sum_request")
+	defer span.Finish()
+
+	ctx, cancel := context.WithCancel(rpcContext(r.Context(), span))
+	defer cancel()
+
+	// stream is opened before upgrade, so unavailable backend is reported with regular error response
+	var stream proxy.SumService_RunningSumClient
+	err := invokeRPC(ctx, h.sum, h.pool, true, func(conn *grpc.ClientConn) error {
+		var err error
+		stream, err = proxy.NewSumServiceClient(conn).RunningSum(ctx)
+		return err
+	})
+	if err != nil {
+		writeBackendError(w, r, sumServiceName, err)
+		return
+	}
+
+	// connection is tracked before it is hijacked, so it is not missed by graceful shutdown
+	streams.Add(1)
+	defer streams.Done()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// upgrader has already responded with error
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsMaxMessageSize)
+
+	// client is asked to close connection when gateway shuts down, remaining sums are still delivered
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-serviceCtx.Done():
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "Service shutting down"), time.Now().Add(wsWriteTimeout))
+		}
+	}()
+
+	// sums are forwarded to the client by single goroutine, as connection supports one writer at a time
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				if status, e, ok := backendErrorResponse(ctx, sumServiceName, err); ok {
+					writeWebSocketError(conn, status, e)
+				}
+				// unblock reading from the client
+				conn.Close()
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(sumMessage{Sum: resp.Sum}); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	// numbers are forwarded to the stream until client closes connection or sends invalid message
+	closeCode, closeText := websocket.CloseNormalClosure, ""
+	for {
+		var msg numberMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if _, ok := err.(*websocket.CloseError); !ok {
+				closeCode, closeText = websocket.CloseUnsupportedData, "Invalid message"
+			}
+			break
+		}
+		if msg.Value == nil {
+			closeCode, closeText = websocket.CloseUnsupportedData, "Message has no value"
+			break
+		}
+
+		if err := stream.Send(&proxy.NumberRequest{Value: *msg.Value}); err != nil {
+			// failure is reported by the receiving goroutine
+			break
+		}
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot close running sum stream")
+	}
+
+	// remaining sums are delivered unless backend does not finish the stream in time
+	select {
+	case <-done:
+	case <-time.After(wsWriteTimeout):
+		cancel()
+		<-done
+	}
+
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, closeText), time.Now().Add(wsWriteTimeout))
+}
+
+// helper function to send error envelope to WebSocket client and close the connection,
+// error code is used as close reason
+func writeWebSocketError(conn *websocket.Conn, status int, e errorResponse) {
+	closeCode := websocket.CloseInternalServerErr
+	if status < http.StatusInternalServerError {
+		closeCode = websocket.ClosePolicyViolation
+	}
+	deadline := time.Now().Add(wsWriteTimeout)
+
+	conn.SetWriteDeadline(deadline)
+	conn.WriteJSON(errorEnvelope{Error: e})
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, e.Code), deadline)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	"github.com/gkarlik/quark-go/logger"
+	auth "github.com/gkarlik/quark-go/middleware/auth/jwt"
+)
+
+// how often revoked access tokens are reloaded from the database, so revocations
+// made by other gateway instances are applied as well
+const revocationSyncInterval = 10 * time.Second
+
+// token settings loaded from environment variables in main
+var (
+	tokenSecret     string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+)
+
+var revokedTokens = &revocationList{tokens: map[string]time.Time{}}
+
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// revocationList keeps identifiers of revoked access tokens until they expire
+type revocationList struct {
+	sync.RWMutex
+	tokens map[string]time.Time
+}
+
+func (rl *revocationList) add(id string, expiresAt time.Time) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	rl.tokens[id] = expiresAt
+}
+
+func (rl *revocationList) contains(id string) bool {
+	rl.RLock()
+	defer rl.RUnlock()
+
+	expiresAt, ok := rl.tokens[id]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (rl *revocationList) reload(tokens []model.RevokedToken) {
+	m := make(map[string]time.Time, len(tokens))
+	for _, t := range tokens {
+		m[t.TokenID] = t.ExpiresAt
+	}
+
+	rl.Lock()
+	defer rl.Unlock()
+
+	rl.tokens = m
+}
+
+// helper function to periodically load revoked access tokens from the database
+func syncRevokedTokens() {
+	repo := model.NewTokenRepository(srv.Database())
+	for {
+		tokens, err := repo.RevokedAccessTokens()
+		if err != nil {
+			srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot load revoked tokens")
+		} else {
+			revokedTokens.reload(tokens)
+		}
+		time.Sleep(revocationSyncInterval)
+	}
+}
+
+// middleware rejecting access tokens which were revoked before expiry
+func checkRevoked(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := userClaims(r); ok && claims.Id != "" && revokedTokens.contains(claims.Id) {
+			writeError(w, http.StatusUnauthorized, "Token has been revoked")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// helper function to generate signed, short-lived access token for user
+func issueAccessToken(user *model.User) (string, error) {
+	id, err := model.NewTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := auth.Claims{
+		Username: user.Login,
+		Properties: map[string]interface{}{
+			rolesProperty:       user.RoleNames(),
+			permissionsProperty: user.PermissionList(),
+			planProperty:        user.PlanName(plans.all()),
+		},
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			Issuer:    srv.Info().Address.String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(accessTokenTTL).Unix(),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(tokenSecret))
+}
+
+// helper function to write access and refresh token pair
+func writeTokens(w http.ResponseWriter, user *model.User, refreshToken string) {
+	token, err := issueAccessToken(user)
+	if err != nil {
+		srv.Log().Error(err)
+
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	})
+}
+
+// function to handle login with user credentials
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials auth.Credentials
+	if !decodeJSON(w, r, &credentials) {
+		return
+	}
+
+	context := srv.Database()
+
+	users := model.NewUserRepository(context)
+	user, err := users.VerifyCredentials(credentials.Username, credentials.Password)
+	if upgradeErr, ok := err.(*model.PasswordUpgradeError); ok {
+		// failed upgrade must not block login - it will be retried next time
+		srv.Log().ErrorWithFields(logger.Fields{
+			"error": upgradeErr.Err,
+			"user":  user.ID,
+		}, "Cannot upgrade password hash")
+
+		err = nil
+	}
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, model.ErrInvalidCredentials.Error())
+		return
+	}
+
+	if err := users.LoadRoles(user); err != nil {
+		srv.Log().Error(err)
+
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	refreshToken, err := model.NewTokenRepository(context).IssueRefreshToken(user.ID, "", refreshTokenTTL)
+	if err != nil {
+		srv.Log().Error(err)
+
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	writeTokens(w, user, refreshToken)
+}
+
+// function to handle exchange of refresh token for new token pair
+func refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req refreshTokenRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	context := srv.Database()
+
+	refreshToken, previous, err := model.NewTokenRepository(context).RotateRefreshToken(req.RefreshToken, refreshTokenTTL)
+	if err != nil {
+		if err != model.ErrInvalidToken {
+			srv.Log().Error(err)
+		}
+		writeError(w, http.StatusUnauthorized, model.ErrInvalidToken.Error())
+		return
+	}
+
+	// roles are reloaded, so permission changes take effect with the next refresh
+	users := model.NewUserRepository(context)
+	user, err := users.FindByID(previous.UserID)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, model.ErrInvalidToken.Error())
+		return
+	}
+
+	if err := users.LoadRoles(user); err != nil {
+		srv.Log().Error(err)
+
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	writeTokens(w, user, refreshToken)
+}
+
+// function to handle logout - revokes current access token and, if given, refresh token
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req refreshTokenRequest
+	if r.ContentLength != 0 && !decodeJSON(w, r, &req) {
+		return
+	}
+
+	context := srv.Database()
+
+	user, ok := currentUser(w, r, model.NewUserRepository(context))
+	if !ok {
+		return
+	}
+	claims, _ := userClaims(r)
+
+	repo := model.NewTokenRepository(context)
+
+	// refresh token is checked first, so rejected request revokes nothing
+	var refreshToken *model.RefreshToken
+	if req.RefreshToken != "" {
+		var err error
+		refreshToken, err = repo.FindRefreshToken(req.RefreshToken)
+		switch {
+		case err == model.ErrInvalidToken:
+			// unknown refresh token is not an error - the user is logged out anyway
+		case err != nil:
+			srv.Log().Error(err)
+
+			writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			return
+		case refreshToken.UserID != user.ID:
+			writeError(w, http.StatusForbidden, "Refresh token belongs to other user")
+			return
+		}
+	}
+
+	if claims.Id != "" {
+		expiresAt := time.Unix(claims.ExpiresAt, 0)
+		if err := repo.RevokeAccessToken(claims.Id, expiresAt); err != nil {
+			srv.Log().Error(err)
+
+			writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			return
+		}
+		revokedTokens.add(claims.Id, expiresAt)
+	}
+
+	if refreshToken != nil {
+		if err := repo.RevokeRefreshToken(refreshToken); err != nil {
+			srv.Log().Error(err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
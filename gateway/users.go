@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gkarlik/quark-go-example/gateway/messages"
+	"github.com/gkarlik/quark-go-example/gateway/model"
+	"github.com/gkarlik/quark-go/data/access/rdbms"
+	"github.com/gkarlik/quark-go/logger"
+	auth "github.com/gkarlik/quark-go/middleware/auth/jwt"
+)
+
+// key under which authentication middleware stores user claims in request context
+const userKey = "USER_KEY"
+
+// header passing login of authenticated user to backend services
+const userHeader = "X-User"
+
+// public representation of model.User
+type userProfile struct {
+	ID    uint   `json:"id"`
+	Login string `json:"login"`
+}
+
+type registerRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// helper function to translate user repository errors to responses
+func writeUserError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case model.ValidationErrors:
+		writeErrorResponse(w, http.StatusUnprocessableEntity, errorResponse{Message: "Validation failed", Errors: e})
+	default:
+		switch err {
+		case model.ErrLoginTaken:
+			writeError(w, http.StatusConflict, err.Error())
+		case model.ErrInvalidCredentials:
+			writeError(w, http.StatusForbidden, err.Error())
+		default:
+			srv.Log().Error(err)
+
+			writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+	}
+}
+
+// helper function to get claims of authenticated user
+func userClaims(r *http.Request) (*auth.Claims, bool) {
+	claims, ok := r.Context().Value(userKey).(*auth.Claims)
+	return claims, ok
+}
+
+// helper function to get login of authenticated user passed to backend services
+func userName(ctx context.Context) string {
+	if claims, ok := ctx.Value(userKey).(*auth.Claims); ok {
+		return claims.Username
+	}
+	return ""
+}
+
+// helper function to load authenticated user from the database
+func currentUser(w http.ResponseWriter, r *http.Request, repo *model.UserRepository) (*model.User, bool) {
+	claims, ok := userClaims(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return nil, false
+	}
+
+	user, err := repo.FindByLogin(claims.Username)
+	if model.IsNotFound(err) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return nil, false
+	}
+	if err != nil {
+		srv.Log().Error(err)
+
+		writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return nil, false
+	}
+	return user, true
+}
+
+// helper function to decode JSON request body
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON body")
+		return false
+	}
+	return true
+}
+
+// function to handle user self-registration
+func registerUserHandler(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	context := srv.Database()
+
+	// event is stored together with the user, so it is published if and only if user is registered
+	var user *model.User
+	err := model.InTransaction(context, func(tx rdbms.DbContext) error {
+		var err error
+		if user, err = model.NewUserRepository(tx).Create(req.Login, req.Password); err != nil {
+			return err
+		}
+		return stageMessage(r.Context(), tx, messages.UsersTopic, &messages.UserRegistered{UserID: user.ID, Login: user.Login})
+	})
+	if err != nil {
+		// failed statement aborts the transaction, so user registered in the meantime is looked up outside of it
+		if _, ok := err.(model.ValidationErrors); !ok && err != model.ErrLoginTaken {
+			if _, ferr := model.NewUserRepository(context).FindByLogin(req.Login); ferr == nil {
+				err = model.ErrLoginTaken
+			}
+		}
+		writeUserError(w, err)
+		return
+	}
+
+	srv.Log().InfoWithFields(logger.Fields{"login": user.Login}, "User registered")
+
+	writeJSON(w, http.StatusCreated, userProfile{ID: user.ID, Login: user.Login})
+}
+
+// function to handle retrieving profile of authenticated user
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	context := srv.Database()
+
+	user, ok := currentUser(w, r, model.NewUserRepository(context))
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userProfile{ID: user.ID, Login: user.Login})
+}
+
+// function to handle password change of authenticated user
+func changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req changePasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	context := srv.Database()
+
+	repo := model.NewUserRepository(context)
+	user, ok := currentUser(w, r, repo)
+	if !ok {
+		return
+	}
+
+	err := model.InTransaction(context, func(tx rdbms.DbContext) error {
+		if err := model.NewUserRepository(tx).ChangePassword(user, req.CurrentPassword, req.NewPassword); err != nil {
+			return err
+		}
+		return stageMessage(r.Context(), tx, messages.UsersTopic, &messages.PasswordChanged{UserID: user.ID, Login: user.Login})
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	// sessions started with the old password must not be refreshed anymore
+	if err := model.NewTokenRepository(context).RevokeUserTokens(user.ID); err != nil {
+		srv.Log().Error(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// function to handle removal of authenticated user account
+func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	context := srv.Database()
+
+	repo := model.NewUserRepository(context)
+	user, ok := currentUser(w, r, repo)
+	if !ok {
+		return
+	}
+
+	err := model.InTransaction(context, func(tx rdbms.DbContext) error {
+		if err := model.NewTokenRepository(tx).RevokeUserTokens(user.ID); err != nil {
+			return err
+		}
+		if err := model.NewUserRepository(tx).DeleteUser(user); err != nil {
+			return err
+		}
+		return stageMessage(r.Context(), tx, messages.UsersTopic, &messages.UserDeleted{UserID: user.ID, Login: user.Login})
+	})
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	srv.Log().InfoWithFields(logger.Fields{"login": user.Login}, "User deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
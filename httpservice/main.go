@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gkarlik/quark-go"
-	"github.com/gkarlik/quark-go/broker/rabbitmq"
+	"github.com/gkarlik/quark-go-example/httpservice/health"
+	"github.com/gkarlik/quark-go-example/httpservice/messages"
+	"github.com/gkarlik/quark-go-example/httpservice/publisher"
+	"github.com/gkarlik/quark-go-example/httpservice/shutdown"
 	"github.com/gkarlik/quark-go/logger"
 	"github.com/gkarlik/quark-go/metrics"
 	"github.com/gkarlik/quark-go/metrics/prometheus"
@@ -16,18 +22,31 @@ import (
 	"github.com/gkarlik/quark-go/service/discovery/consul"
 	"github.com/gkarlik/quark-go/service/trace/zipkin"
 	"github.com/gorilla/mux"
+	"github.com/hashicorp/consul/api"
 	"github.com/opentracing/opentracing-go"
 )
 
 // multiplyService service based on quark.ServiceBase
 type multiplyService struct {
 	*quark.ServiceBase
+	events *publisher.Publisher
 }
 
+// header carrying time left to deadline of the request set by the gateway
+const timeoutHeader = "X-Request-Timeout"
+
 var (
-	errorCounter metrics.Counter
+	errorCounter   metrics.Counter
+	timeoutCounter metrics.Counter
 )
 
+// multiplyResponse is JSON representation of multiplication result
+type multiplyResponse struct {
+	Operation string  `json:"operation"`
+	Operands  []int64 `json:"operands"`
+	Result    int64   `json:"result"`
+}
+
 // helper function to initialize multiplyService service
 func createMultiplyService() *multiplyService {
 	// load settings from environment variables
@@ -56,12 +75,13 @@ func createMultiplyService() *multiplyService {
 			quark.Address(addr),
 			quark.Discovery(consul.NewServiceDiscovery(discovery)),
 			quark.Metrics(prometheus.NewMetricsExposer()),
-			quark.Tracer(zipkin.NewTracer(tAddr, name, addr)),
-			quark.Broker(rabbitmq.NewMessageBroker(bAddr))),
+			quark.Tracer(zipkin.NewTracer(tAddr, name, addr))),
+		events: publisher.New(bAddr, messages.Exchange),
 	}
 	m.Log().SetLevel(logger.DebugLevel)
 
 	errorCounter = m.Metrics().CreateCounter("error_count", "Counting errors")
+	timeoutCounter = m.Metrics().CreateCounter("timeout_count", "Counting requests aborted after deadline")
 
 	return m
 }
@@ -71,8 +91,13 @@ var srv = createMultiplyService()
 func main() {
 	defer srv.Dispose()
 
+	gracePeriod, err := time.ParseDuration(quark.GetEnvVar("MULTIPLY_SERVICE_SHUTDOWN_GRACE_PERIOD"))
+	if err != nil || gracePeriod <= 0 {
+		panic("Incorrect shutdown grace period value!")
+	}
+
 	// register service in service discovery catalog
-	err := srv.Discovery().RegisterService(sd.WithInfo(srv.Info()))
+	err = srv.Discovery().RegisterService(sd.WithInfo(srv.Info()))
 	if err != nil {
 		srv.Log().ErrorWithFields(logger.Fields{
 			"err": err,
@@ -80,39 +105,102 @@ func main() {
 
 		panic("Cannot register service!")
 	}
+	// instance is deregistered only when it is not alive, unavailable dependencies just take it out of rotation
+	if err := health.RegisterChecks(quark.GetEnvVar("DISCOVERY"), srv.Info().Name, srv.Info().Address.Host, map[string]api.AgentServiceCheck{
+		"live": {
+			HTTP:                           "http://" + srv.Info().Address.Host + "/healthz",
+			Interval:                       health.ConsulCheckInterval,
+			Timeout:                        health.ConsulCheckTimeout,
+			DeregisterCriticalServiceAfter: health.ConsulDeregisterAfter,
+		},
+		"ready": {
+			HTTP:     "http://" + srv.Info().Address.Host + "/readyz",
+			Interval: health.ConsulCheckInterval,
+			Timeout:  health.ConsulCheckTimeout,
+		},
+	}); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot register health checks")
+	}
+
+	// dependencies required to serve requests
+	readiness := health.Checks{
+		"broker":    health.Broker(quark.GetEnvVar("BROKER")),
+		"discovery": health.Discovery(quark.GetEnvVar("DISCOVERY")),
+	}
 
 	r := mux.NewRouter()
-	r.HandleFunc("/multiply/{a:[0-9]+}/{b:[0-9]+}", mulitplyHandler)
+	r.Handle("/multiply/{a:-?[0-9]+}/{b:-?[0-9]+}", withDeadline(http.HandlerFunc(mulitplyHandler)))
 	r.Handle("/metrics", srv.Metrics().ExposeHandler())
+	r.HandleFunc("/healthz", health.LivenessHandler)
+	r.Handle("/readyz", health.ReadinessHandler(readiness, shuttingDown))
 
+	// messages are consumed until service shuts down, messages being processed are finished first
+	c := createConsumer()
+	consumers.Add(1)
 	go func() {
-		srv.Log().Info("Waiting for incomming messages")
-
-		messages, err := srv.Broker().Subscribe(context.Background(), "SampleTopic")
-		if err != nil {
-			srv.Log().ErrorWithFields(logger.Fields{
-				"error": err,
-			}, "Cannot subscribe to messages with topic = 'SampleTopic'")
+		defer consumers.Done()
 
-			return
-		}
-		for msg := range messages {
-			srv.Log().InfoWithFields(logger.Fields{
-				"topic": msg.Topic,
-				"value": string(msg.Value.([]byte)),
-			}, "Message received")
-		}
+		consume(c)
 	}()
 
 	srv.Log().InfoWithFields(logger.Fields{
 		"addr": srv.Info().Address.Host,
 	}, "Service initialized. Listening for incomming connections")
 
-	srv.Log().Fatal(http.ListenAndServe(srv.Info().Address.Host, r))
+	server := &http.Server{Addr: srv.Info().Address.Host, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			srv.Log().Fatal(err)
+		}
+	}()
+
+	sig := shutdown.WaitForSignal()
+	srv.Log().InfoWithFields(logger.Fields{"signal": sig}, "Shutting down")
+
+	shutdownGracefully(server, gracePeriod)
+}
+
+// middleware applying deadline passed by the caller to the request context
+func withDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Get(timeoutHeader)
+		if h == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		timeout, err := time.ParseDuration(h)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s header", timeoutHeader), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// helper function to abort request when its deadline has passed or caller is gone
+func aborted(w http.ResponseWriter, r *http.Request) bool {
+	if err := r.Context().Err(); err != nil {
+		timeoutCounter.Inc()
+
+		srv.Log().WarnWithFields(logger.Fields{
+			"error": err,
+		}, "Request aborted")
+
+		http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+		return true
+	}
+	return false
 }
 
 // function to handle multiplication of two integers
 func mulitplyHandler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
 	// extract and start request tracing span
 	span, _ := srv.Tracer().ExtractSpan("mul_handler", opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
 	defer span.Finish()
@@ -120,6 +208,10 @@ func mulitplyHandler(w http.ResponseWriter, r *http.Request) {
 	// multiply two integers
 	srv.Log().Info("Executing multiply function")
 
+	if aborted(w, r) {
+		return
+	}
+
 	if time.Now().Second()%2 == 0 {
 		errorCounter.Inc()
 
@@ -129,12 +221,51 @@ func mulitplyHandler(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 
-	a, _ := strconv.Atoi(vars["a"])
-	b, _ := strconv.Atoi(vars["b"])
+	// operands and result out of int64 range are rejected instead of silently wrapped
+	a, errA := strconv.ParseInt(vars["a"], 10, 64)
+	b, errB := strconv.ParseInt(vars["b"], 10, 64)
+	if errA != nil || errB != nil {
+		http.Error(w, "Operand out of int64 range", http.StatusBadRequest)
+		return
+	}
+	result, ok := multiply(a, b)
+	if !ok {
+		http.Error(w, "Result out of int64 range", http.StatusBadRequest)
+		return
+	}
+
+	// generate response unless caller stopped waiting for it
+	if aborted(w, r) {
+		return
+	}
+	publishCalculation(r, span, "multiply", []int64{a, b}, result, start)
+
+	// JSON document is returned to clients asking for it, e.g. the gateway
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(multiplyResponse{
+			Operation: "multiply",
+			Operands:  []int64{a, b},
+			Result:    result,
+		})
+		return
+	}
 
-	// generate response
-	resp := fmt.Sprintf("%d * %d = %d", a, b, a*b)
+	resp := fmt.Sprintf("%d * %d = %d", a, b, result)
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(resp))
 }
+
+// helper function to multiply integers, reports false on overflow
+func multiply(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return c, true
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/httpservice/consumer"
+	"github.com/gkarlik/quark-go-example/httpservice/messages"
+	"github.com/gkarlik/quark-go-example/httpservice/publisher"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gkarlik/quark-go/service/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// interval of reconnecting consumer to the broker
+const reconnectInterval = 5 * time.Second
+
+// headers of request ID and authenticated user passed by the gateway
+const (
+	requestIDHeader = "X-Request-ID"
+	userHeader      = "X-User"
+)
+
+// key of trace ID propagated by zipkin tracer
+const traceIDKey = "x-b3-traceid"
+
+// time limit of publishing message, including broker confirmation
+const publishTimeout = 10 * time.Second
+
+// Dispose closes broker connection and releases service resources
+func (m *multiplyService) Dispose() {
+	m.events.Close()
+	m.ServiceBase.Dispose()
+}
+
+// helper function to create consumer of CalculationPerformed events with settings loaded from environment variables
+func createConsumer() *consumer.Consumer {
+	workers, err := strconv.Atoi(quark.GetEnvVar("MULTIPLY_SERVICE_CONSUMER_WORKERS"))
+	if err != nil || workers < 1 {
+		panic("Incorrect consumer workers value!")
+	}
+	prefetch, err := strconv.Atoi(quark.GetEnvVar("MULTIPLY_SERVICE_CONSUMER_PREFETCH"))
+	if err != nil || prefetch < 1 {
+		panic("Incorrect consumer prefetch value!")
+	}
+	attempts, err := strconv.Atoi(quark.GetEnvVar("MULTIPLY_SERVICE_CONSUMER_MAX_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		panic("Incorrect consumer max attempts value!")
+	}
+	baseDelay, err := time.ParseDuration(quark.GetEnvVar("MULTIPLY_SERVICE_CONSUMER_RETRY_DELAY"))
+	if err != nil {
+		panic("Incorrect consumer retry delay value!")
+	}
+	maxDelay, err := time.ParseDuration(quark.GetEnvVar("MULTIPLY_SERVICE_CONSUMER_MAX_RETRY_DELAY"))
+	if err != nil {
+		panic("Incorrect consumer max retry delay value!")
+	}
+
+	// instances of the service share the queue, so each event is handled once
+	return consumer.New(quark.GetEnvVar("BROKER"), messages.Exchange, messages.CalculationsTopic, calculationHandler,
+		consumer.Group(srv.Info().Name),
+		consumer.DeadLetterTopic(quark.GetEnvVar("MULTIPLY_SERVICE_DEAD_LETTER_TOPIC")),
+		consumer.Workers(workers),
+		consumer.Prefetch(prefetch),
+		consumer.Retry(consumer.RetryPolicy{
+			MaxAttempts: attempts,
+			BaseDelay:   baseDelay,
+			MaxDelay:    maxDelay,
+		}),
+		consumer.WithMetrics(consumer.Metrics{
+			Processed:    srv.Metrics().CreateCounter("messages_processed", "Number of messages processed"),
+			Failed:       srv.Metrics().CreateCounter("messages_failed", "Number of failed attempts of processing messages"),
+			Redelivered:  srv.Metrics().CreateCounter("messages_redelivered", "Number of messages redelivered by broker"),
+			DeadLettered: srv.Metrics().CreateCounter("messages_dead_lettered", "Number of messages moved to dead-letter topic"),
+		}))
+}
+
+// function to consume messages until service shuts down, consumer is reconnected when broker connection is lost
+func consume(c *consumer.Consumer) {
+	srv.Log().Info("Waiting for incomming messages")
+
+	for {
+		err := c.Run(serviceCtx)
+		if serviceCtx.Err() != nil {
+			return
+		}
+		srv.Log().ErrorWithFields(logger.Fields{
+			"error": err,
+			"topic": messages.CalculationsTopic,
+		}, "Cannot consume messages")
+
+		select {
+		case <-serviceCtx.Done():
+			return
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+// function to handle CalculationPerformed events. Messages which cannot be decoded, e.g. of unknown
+// type or incompatible schema version, are moved to dead-letter topic at once.
+func calculationHandler(ctx context.Context, msg consumer.Message) error {
+	e, m, err := messages.Registered.Unmarshal(msg.Body)
+	if err != nil {
+		return consumer.Permanent(err)
+	}
+
+	// continue trace of the publisher
+	span, _ := srv.Tracer().ExtractSpan("calculation_handler", opentracing.TextMap, e.Trace)
+	defer span.Finish()
+
+	event, ok := m.(*messages.CalculationPerformed)
+	if !ok {
+		return consumer.Permanent(fmt.Errorf("Unexpected message '%s'", e.Type))
+	}
+
+	srv.Log().InfoWithFields(logger.Fields{
+		"topic":          msg.Topic,
+		"id":             e.ID,
+		"correlation_id": e.CorrelationID,
+		"operation":      event.Operation,
+		"operands":       event.Operands,
+		"result":         event.Result,
+		"user":           event.User,
+		"latency":        event.Latency,
+		"trace_id":       event.TraceID,
+		"attempt":        msg.Attempt,
+		"redelivered":    msg.Redelivered,
+	}, "Calculation performed")
+
+	return nil
+}
+
+// helper function to publish message wrapped in envelope carrying trace context. Message starting
+// new conversation is given empty correlation ID, its own ID is used instead.
+func publishMessage(ctx context.Context, topic string, m messages.Message, traceContext opentracing.TextMapCarrier, correlationID string) error {
+	e, err := messages.Registered.Wrap(m)
+	if err != nil {
+		return err
+	}
+
+	e.CorrelationID = correlationID
+	if e.CorrelationID == "" {
+		e.CorrelationID = e.ID
+	}
+	for k, v := range traceContext {
+		e.Trace[k] = v
+	}
+
+	data, err := e.Marshal()
+	if err != nil {
+		return err
+	}
+	return srv.events.Publish(ctx, topic, publisher.Message{ID: e.ID, ContentType: "application/json", Body: data})
+}
+
+// helper function to publish CalculationPerformed event. Event is published in background, so broker
+// does not delay the response, and events still being published are drained during shutdown.
+func publishCalculation(r *http.Request, span trace.Span, operation string, operands []int64, result int64, start time.Time) {
+	traceContext := opentracing.TextMapCarrier{}
+	if err := srv.Tracer().InjectSpan(span, opentracing.TextMap, traceContext); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot inject trace context")
+	}
+
+	event := &messages.CalculationPerformed{
+		Operation: operation,
+		Operands:  operands,
+		Result:    result,
+		User:      r.Header.Get(userHeader),
+		Latency:   time.Since(start),
+		TraceID:   traceContext[traceIDKey],
+	}
+	// request of the gateway starts the conversation
+	requestID := r.Header.Get(requestIDHeader)
+
+	publishers.Add(1)
+	go func() {
+		defer publishers.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		defer cancel()
+
+		if err := publishMessage(ctx, messages.CalculationsTopic, event, traceContext, requestID); err != nil {
+			srv.Log().ErrorWithFields(logger.Fields{
+				"error": err,
+				"topic": messages.CalculationsTopic,
+			}, "Cannot publish message")
+		}
+	}()
+}
@@ -0,0 +1,122 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Message is a message published to the topic
+type Message struct {
+	ID          string
+	ContentType string
+	Body        []byte
+}
+
+// Publisher publishes messages to topic exchange of RabbitMQ, each message is routed by its topic
+// to queues of all subscribers. Publishing waits until broker confirms it accepted the message.
+type Publisher struct {
+	url      string
+	exchange string
+
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+// New creates publisher to the exchange, broker is given by AMQP URL. Connection is opened
+// with first message and reopened after it is lost.
+func New(url, exchange string) *Publisher {
+	return &Publisher{
+		url:      url,
+		exchange: exchange,
+	}
+}
+
+// Publish publishes message to the topic and waits for broker confirmation. Message is not
+// guaranteed to be rejected when context is done meanwhile, so publishing again may duplicate it.
+func (p *Publisher) Publish(ctx context.Context, topic string, m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+
+	err := p.ch.Publish(p.exchange, topic, false, false, amqp.Publishing{
+		ContentType:  m.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    m.ID,
+		Timestamp:    time.Now(),
+		Body:         m.Body,
+	})
+	if err != nil {
+		p.close()
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		// confirmation would be taken for the next message, so channel is dropped
+		p.close()
+		return ctx.Err()
+	case confirm, ok := <-p.confirms:
+		if !ok {
+			p.close()
+			return errors.New("Broker connection closed")
+		}
+		if !confirm.Ack {
+			return fmt.Errorf("Message not accepted by %s topic", topic)
+		}
+		return nil
+	}
+}
+
+// Close closes broker connection
+func (p *Publisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.close()
+}
+
+// helper function to open connection and channel in confirm mode, exchange is declared the same way
+// as by consumers
+func (p *Publisher) connect() error {
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := ch.ExchangeDeclare(p.exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return err
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return err
+	}
+
+	p.conn, p.ch, p.confirms = conn, ch, ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return nil
+}
+
+// helper function to drop connection, so it is opened again by next message
+func (p *Publisher) close() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn, p.ch, p.confirms = nil, nil, nil
+}
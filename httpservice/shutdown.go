@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gkarlik/quark-go-example/httpservice/shutdown"
+	"github.com/gkarlik/quark-go/logger"
+	sd "github.com/gkarlik/quark-go/service/discovery"
+)
+
+// serviceCtx is cancelled when service starts shutting down, so broker consumers can finish
+var serviceCtx, stopService = context.WithCancel(context.Background())
+
+// broker consumers and messages being published in background, drained during shutdown
+var consumers, publishers sync.WaitGroup
+
+// helper function to report whether service is shutting down
+func shuttingDown() bool {
+	return serviceCtx.Err() != nil
+}
+
+// function to shut down service gracefully. Service is removed from service discovery first, so no new
+// requests are routed to it, then in-flight requests and messages are given grace period to finish.
+func shutdownGracefully(server *http.Server, gracePeriod time.Duration) {
+	if err := srv.Discovery().DeregisterService(sd.WithInfo(srv.Info())); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot deregister service")
+	}
+	stopService()
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	// stop accepting connections and wait for in-flight requests
+	if err := server.Shutdown(ctx); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Requests not drained within grace period")
+		return
+	}
+	if err := shutdown.Wait(ctx, &publishers); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Messages not published within grace period")
+		return
+	}
+	if err := shutdown.Wait(ctx, &consumers); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Messages not drained within grace period")
+		return
+	}
+	srv.Log().Info("Requests and messages drained")
+}
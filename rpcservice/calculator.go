@@ -0,0 +1,213 @@
+package main
+
+import (
+	"io"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	proxy "github.com/gkarlik/quark-go-example/rpcservice/proxies/sum"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// helper function to abort operation when deadline set by the caller (propagated by gRPC)
+// has passed or caller is gone - do not work for nobody
+func abortIfDone(ctx context.Context, operation string) error {
+	switch err := ctx.Err(); err {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return grpc.Errorf(codes.DeadlineExceeded, "%s aborted: %v", operation, err)
+	default:
+		return grpc.Errorf(codes.Canceled, "%s aborted: %v", operation, err)
+	}
+}
+
+// error returned when result does not fit in int64
+func overflow(operation string) error {
+	return grpc.Errorf(codes.OutOfRange, "%s result out of int64 range, use Calculate for big numbers", operation)
+}
+
+// helper function to add integers, reports false on overflow
+func addInt64(a, b int64) (int64, bool) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, false
+	}
+	return a + b, true
+}
+
+// helper function to subtract integers, reports false on overflow
+func subInt64(a, b int64) (int64, bool) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, false
+	}
+	return a - b, true
+}
+
+// helper function to multiply integers, reports false on overflow
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return c, true
+}
+
+// function to handle subtraction of two integers
+func (s *sumService) Subtract(ctx context.Context, r *proxy.OperationRequest) (*proxy.OperationResponse, error) {
+	span := quark.StartRPCSpan(ctx, srv, "subtract_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing subtract function")
+
+	if err := abortIfDone(ctx, "Subtract"); err != nil {
+		return nil, err
+	}
+
+	result, ok := subInt64(r.A, r.B)
+	if !ok {
+		return nil, overflow("Subtract")
+	}
+
+	return &proxy.OperationResponse{
+		Result: result,
+	}, nil
+}
+
+// function to handle division of two integers, result is the nearest double to the exact quotient
+func (s *sumService) Divide(ctx context.Context, r *proxy.OperationRequest) (*proxy.DivideResponse, error) {
+	span := quark.StartRPCSpan(ctx, srv, "divide_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing divide function")
+
+	if err := abortIfDone(ctx, "Divide"); err != nil {
+		return nil, err
+	}
+	if r.B == 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Division by zero")
+	}
+
+	// exact quotient is rounded once, converting operands first would round them as well
+	result, _ := new(big.Rat).SetFrac64(r.A, r.B).Float64()
+	return &proxy.DivideResponse{
+		Result: result,
+	}, nil
+}
+
+// function to handle raising integer to non-negative integer power
+func (s *sumService) Power(ctx context.Context, r *proxy.OperationRequest) (*proxy.OperationResponse, error) {
+	span := quark.StartRPCSpan(ctx, srv, "power_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing power function")
+
+	if err := abortIfDone(ctx, "Power"); err != nil {
+		return nil, err
+	}
+	if r.B < 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Exponent must not be negative")
+	}
+
+	// exponentiation by squaring, square of the base is not needed after the last bit
+	result, base, exp := int64(1), r.A, r.B
+	for exp > 0 {
+		var ok bool
+		if exp&1 == 1 {
+			if result, ok = mulInt64(result, base); !ok {
+				return nil, overflow("Power")
+			}
+		}
+		if exp >>= 1; exp > 0 {
+			if base, ok = mulInt64(base, base); !ok {
+				return nil, overflow("Power")
+			}
+		}
+	}
+
+	return &proxy.OperationResponse{
+		Result: result,
+	}, nil
+}
+
+// function to handle remainder of division of two integers
+func (s *sumService) Modulo(ctx context.Context, r *proxy.OperationRequest) (*proxy.OperationResponse, error) {
+	span := quark.StartRPCSpan(ctx, srv, "modulo_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing modulo function")
+
+	if err := abortIfDone(ctx, "Modulo"); err != nil {
+		return nil, err
+	}
+	if r.B == 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Division by zero")
+	}
+
+	return &proxy.OperationResponse{
+		Result: r.A % r.B,
+	}, nil
+}
+
+// function to handle negation of integer
+func (s *sumService) Negate(ctx context.Context, r *proxy.NegateRequest) (*proxy.OperationResponse, error) {
+	span := quark.StartRPCSpan(ctx, srv, "negate_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing negate function")
+
+	if err := abortIfDone(ctx, "Negate"); err != nil {
+		return nil, err
+	}
+
+	if r.A == math.MinInt64 {
+		return nil, overflow("Negate")
+	}
+
+	return &proxy.OperationResponse{
+		Result: -r.A,
+	}, nil
+}
+
+// function to handle sums of the batch streamed by the client. Results are streamed back as soon
+// as they are computed and failure of single item does not break the stream.
+func (s *sumService) BatchSum(stream proxy.SumService_BatchSumServer) error {
+	span := quark.StartRPCSpan(stream.Context(), srv, "batch_sum_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing batch sum function")
+
+	for {
+		r, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		resp := &proxy.BatchSumResponse{Index: r.Index}
+		sum, ok := addInt64(r.A, r.B)
+		if ok {
+			resp.Sum = sum
+		} else {
+			resp.Code = int32(codes.OutOfRange)
+			resp.Error = "Sum result out of int64 range"
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		// every item of the batch is a calculation of its own
+		if ok {
+			publishCalculation(stream.Context(), span, "sum", []int64{r.A, r.B}, sum, start)
+		}
+	}
+}
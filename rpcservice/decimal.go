@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/gkarlik/quark-go"
+	proxy "github.com/gkarlik/quark-go-example/rpcservice/proxies/sum"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// limits protecting service from operations on huge numbers
+const (
+	maxOperandLength = 1000
+	maxResultBits    = 100000
+	defaultPrecision = 20
+	maxPrecision     = 1000
+)
+
+// decimal operand, e.g. 12, -0.5 - exponent and fraction notations accepted by big.Rat are rejected
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// function to handle operation on decimal numbers of arbitrary precision
+func (s *sumService) Calculate(ctx context.Context, r *proxy.DecimalRequest) (*proxy.DecimalResponse, error) {
+	span := quark.StartRPCSpan(ctx, srv, "calculate_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing calculate function")
+
+	if err := abortIfDone(ctx, "Calculate"); err != nil {
+		return nil, err
+	}
+
+	precision := int(r.Precision)
+	if precision == 0 {
+		precision = defaultPrecision
+	}
+	if precision < 0 || precision > maxPrecision {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Precision must be between 0 and %d (0 = default)", maxPrecision)
+	}
+
+	a, err := parseDecimal("A", r.A)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *big.Rat
+	if r.Operation == "negate" {
+		result = new(big.Rat).Neg(a)
+	} else {
+		b, err := parseDecimal("B", r.B)
+		if err != nil {
+			return nil, err
+		}
+		if result, err = calculate(r.Operation, a, b); err != nil {
+			return nil, err
+		}
+	}
+
+	return &proxy.DecimalResponse{
+		Result: formatDecimal(result, precision),
+	}, nil
+}
+
+// helper function to parse decimal operand
+func parseDecimal(name, s string) (*big.Rat, error) {
+	if len(s) > maxOperandLength {
+		return nil, grpc.Errorf(codes.OutOfRange, "Operand %s exceeds %d characters", name, maxOperandLength)
+	}
+	if !decimalPattern.MatchString(s) {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Operand %s is not a decimal number: '%s'", name, s)
+	}
+
+	r, _ := new(big.Rat).SetString(s)
+	return r, nil
+}
+
+// helper function to perform binary operation on decimals
+func calculate(operation string, a, b *big.Rat) (*big.Rat, error) {
+	switch operation {
+	case "sum":
+		return new(big.Rat).Add(a, b), nil
+	case "subtract":
+		return new(big.Rat).Sub(a, b), nil
+	case "multiply":
+		return new(big.Rat).Mul(a, b), nil
+	case "divide":
+		if b.Sign() == 0 {
+			return nil, grpc.Errorf(codes.InvalidArgument, "Division by zero")
+		}
+		return new(big.Rat).Quo(a, b), nil
+	case "modulo":
+		if b.Sign() == 0 {
+			return nil, grpc.Errorf(codes.InvalidArgument, "Division by zero")
+		}
+		// remainder has sign of the dividend, as % operator on integers
+		q := new(big.Rat).Quo(a, b)
+		t := new(big.Int).Quo(q.Num(), q.Denom())
+		return new(big.Rat).Sub(a, new(big.Rat).Mul(b, new(big.Rat).SetInt(t))), nil
+	case "power":
+		return power(a, b)
+	}
+	return nil, grpc.Errorf(codes.InvalidArgument, "Unknown operation '%s'", operation)
+}
+
+// helper function to raise decimal to integer power
+func power(a, b *big.Rat) (*big.Rat, error) {
+	if !b.IsInt() {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Exponent must be an integer")
+	}
+
+	exp := new(big.Int).Abs(b.Num())
+	bits := a.Num().BitLen()
+	if d := a.Denom().BitLen(); d > bits {
+		bits = d
+	}
+	// bit length of the result is roughly bit length of the base multiplied by exponent
+	if bits > 1 && (!exp.IsInt64() || exp.Int64() > int64(maxResultBits/(bits-1))) {
+		return nil, grpc.Errorf(codes.OutOfRange, "Power result exceeds %d bits", maxResultBits)
+	}
+	if b.Sign() < 0 && a.Sign() == 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Division by zero")
+	}
+
+	num := new(big.Int).Exp(a.Num(), exp, nil)
+	denom := new(big.Int).Exp(a.Denom(), exp, nil)
+	if b.Sign() < 0 {
+		num, denom = denom, num
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
+}
+
+// helper function to format decimal - terminating decimals are formatted exactly, other ones
+// are rounded to precision digits. Trailing zeros of the fraction are removed.
+func formatDecimal(r *big.Rat, precision int) string {
+	digits := precision
+	if n, ok := fractionDigits(r.Denom()); ok {
+		digits = n
+	}
+
+	s := r.FloatString(digits)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+// helper function to count fractional digits of terminating decimal with given denominator.
+// Decimal terminates when denominator has no prime factors other than 2 and 5.
+func fractionDigits(denom *big.Int) (int, bool) {
+	d := new(big.Int).Set(denom)
+	r := new(big.Int)
+
+	count := func(p int64) int {
+		n, f := 0, big.NewInt(p)
+		for {
+			q, m := new(big.Int).QuoRem(d, f, r)
+			if m.Sign() != 0 {
+				return n
+			}
+			d, n = q, n+1
+		}
+	}
+
+	twos, fives := count(2), count(5)
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
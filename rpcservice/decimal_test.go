@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		operation string
+		precision int
+		result    string
+	}{
+		{"0.1", "0.2", "sum", 20, "0.3"},
+		{"1.50", "2.5", "sum", 20, "4"},
+		{"1", "8", "divide", 2, "0.125"},
+		{"1", "3", "divide", 5, "0.33333"},
+		{"2", "3", "divide", 3, "0.667"},
+		{"-1", "3", "divide", 2, "-0.33"},
+		{"-0.001", "1000", "divide", 2, "-0.000001"},
+		{"1", "-1000000", "divide", 20, "-0.000001"},
+		{"-1", "1000", "divide", 5, "-0.001"},
+		{"-0.0001", "3", "divide", 2, "0"},
+		{"7.5", "2", "modulo", 20, "1.5"},
+		{"-7.5", "2", "modulo", 20, "-1.5"},
+		{"0.5", "-2", "power", 20, "4"},
+		{"2", "10", "power", 20, "1024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.operation+" "+tt.b, func(t *testing.T) {
+			a, _ := new(big.Rat).SetString(tt.a)
+			b, _ := new(big.Rat).SetString(tt.b)
+
+			r, err := calculate(tt.operation, a, b)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if s := formatDecimal(r, tt.precision); s != tt.result {
+				t.Errorf("Expected %s, got %s", tt.result, s)
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		s    string
+		code codes.Code
+	}{
+		{"12", codes.OK},
+		{"-0.5", codes.OK},
+		{"1e3", codes.InvalidArgument},
+		{"1/2", codes.InvalidArgument},
+		{".5", codes.InvalidArgument},
+		{"", codes.InvalidArgument},
+		{string(make([]byte, maxOperandLength+1)), codes.OutOfRange},
+	}
+
+	for _, tt := range tests {
+		_, err := parseDecimal("A", tt.s)
+		if code := grpc.Code(err); code != tt.code {
+			t.Errorf("Operand '%.10s': expected %s, got %s", tt.s, tt.code, code)
+		}
+	}
+}
+
+func TestCalculateErrors(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		operation string
+		code      codes.Code
+	}{
+		{"1", "0", "divide", codes.InvalidArgument},
+		{"1", "0", "modulo", codes.InvalidArgument},
+		{"0", "-1", "power", codes.InvalidArgument},
+		{"2", "0.5", "power", codes.InvalidArgument},
+		{"3", "1000000", "power", codes.OutOfRange},
+		{"1", "1", "root", codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		a, _ := new(big.Rat).SetString(tt.a)
+		b, _ := new(big.Rat).SetString(tt.b)
+
+		_, err := calculate(tt.operation, a, b)
+		if code := grpc.Code(err); code != tt.code {
+			t.Errorf("%s %s %s: expected %s, got %s", tt.a, tt.operation, tt.b, tt.code, code)
+		}
+	}
+}
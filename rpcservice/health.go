@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/rpcservice/health"
+	"github.com/gkarlik/quark-go/logger"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// interval of updating serving status reported by gRPC health service
+const healthUpdateInterval = 5 * time.Second
+
+// standard gRPC health service, instance is not serving until dependencies are checked
+var healthServer = grpchealth.NewServer()
+
+// helper function to update serving status of gRPC health service according to readiness checks
+func watchReadiness(checks health.Checks) {
+	serving := false
+	for {
+		report, ready := checks.Run(context.Background())
+
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if ready {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		// status of the server and of the service
+		healthServer.SetServingStatus("", status)
+		healthServer.SetServingStatus(srv.Info().Name, status)
+
+		if ready != serving {
+			srv.Log().InfoWithFields(logger.Fields{
+				"status": report.Status,
+				"checks": report.Checks,
+			}, "Readiness changed")
+		}
+		serving = ready
+
+		time.Sleep(healthUpdateInterval)
+	}
+}
+
+// function to handle healthcheck command used by Docker - exit code is 0 when local instance is serving.
+// Service is not created, so the command does not connect to its dependencies.
+func healthCommand() int {
+	ctx, cancel := context.WithTimeout(context.Background(), health.CheckTimeout)
+	defer cancel()
+
+	// server listens on host address, resolved the same way as by the service
+	port, err := strconv.Atoi(quark.GetEnvVar("SUM_SERVICE_PORT"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Incorrect port value!")
+		return 1
+	}
+	addr, err := quark.GetHostAddress(port)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	conn, err := grpc.DialContext(ctx, addr.Host, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		fmt.Fprintln(os.Stderr, resp.Status)
+		return 1
+	}
+	return 0
+}
@@ -1,27 +1,32 @@
 package main
 
 import (
-	"math/rand"
+	"os"
 	"strconv"
 	"time"
 
 	"github.com/gkarlik/quark-go"
+	"github.com/gkarlik/quark-go-example/rpcservice/health"
+	"github.com/gkarlik/quark-go-example/rpcservice/messages"
 	proxy "github.com/gkarlik/quark-go-example/rpcservice/proxies/sum"
-	"github.com/gkarlik/quark-go/broker"
-	"github.com/gkarlik/quark-go/broker/rabbitmq"
+	"github.com/gkarlik/quark-go-example/rpcservice/publisher"
+	"github.com/gkarlik/quark-go-example/rpcservice/shutdown"
 	"github.com/gkarlik/quark-go/logger"
 	"github.com/gkarlik/quark-go/metrics/prometheus"
 	sd "github.com/gkarlik/quark-go/service/discovery"
 	"github.com/gkarlik/quark-go/service/discovery/consul"
 	gRPC "github.com/gkarlik/quark-go/service/rpc/grpc"
 	"github.com/gkarlik/quark-go/service/trace/zipkin"
+	"github.com/hashicorp/consul/api"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // sumService service based on quark.ServiceBase
 type sumService struct {
 	*quark.ServiceBase
+	events *publisher.Publisher
 }
 
 // helper function to initialize sumService service
@@ -52,40 +57,65 @@ func createSumService() *sumService {
 			quark.Address(addr),
 			quark.Discovery(consul.NewServiceDiscovery(discovery)),
 			quark.Metrics(prometheus.NewMetricsExposer()),
-			quark.Tracer(zipkin.NewTracer(tAddr, name, addr)),
-			quark.Broker(rabbitmq.NewMessageBroker(bAddr))),
+			quark.Tracer(zipkin.NewTracer(tAddr, name, addr))),
+		events: publisher.New(bAddr, messages.Exchange),
 	}
 	s.Log().SetLevel(logger.DebugLevel)
 
 	return s
 }
 
-var srv = createSumService()
+// sum service, created in main after maintenance commands which do not need it
+var srv *sumService
 
 // function to handle sum of two integers
 func (s *sumService) Sum(ctx context.Context, r *proxy.SumRequest) (*proxy.SumResponse, error) {
 	// extract and start request tracing span
+	start := time.Now()
 	span := quark.StartRPCSpan(ctx, srv, "sum_handler")
 	defer span.Finish()
 
 	// sum two integers
 	srv.Log().Info("Executing sum function")
 
+	if err := abortIfDone(ctx, "Sum"); err != nil {
+		return nil, err
+	}
+
+	sum, ok := addInt64(r.A, r.B)
+	if !ok {
+		return nil, overflow("Sum")
+	}
+	publishCalculation(ctx, span, "sum", []int64{r.A, r.B}, sum, start)
+
 	return &proxy.SumResponse{
-		Sum: r.A + r.B,
+		Sum: sum,
 	}, nil
 }
 
 // function to register service in gRPC server
 func (s *sumService) RegisterServiceInstance(server interface{}, serviceInstance interface{}) error {
 	proxy.RegisterSumServiceServer(server.(*grpc.Server), serviceInstance.(proxy.SumServiceServer))
+	healthpb.RegisterHealthServer(server.(*grpc.Server), healthServer)
+	grpcServer.Store(server.(*grpc.Server))
 
 	return nil
 }
 
 func main() {
+	// handle healthcheck command used by Docker
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		os.Exit(healthCommand())
+	}
+	srv = createSumService()
+
+	gracePeriod, err := time.ParseDuration(quark.GetEnvVar("SUM_SERVICE_SHUTDOWN_GRACE_PERIOD"))
+	if err != nil || gracePeriod <= 0 {
+		panic("Incorrect shutdown grace period value!")
+	}
+
 	// register service in service discovery catalog
-	err := srv.Discovery().RegisterService(sd.WithInfo(srv.Info()))
+	err = srv.Discovery().RegisterService(sd.WithInfo(srv.Info()))
 	if err != nil {
 		srv.Log().ErrorWithFields(logger.Fields{
 			"err": err,
@@ -93,34 +123,30 @@ func main() {
 
 		panic("Cannot register service!")
 	}
+	// instance is deregistered only when it does not accept connections, unavailable dependencies
+	// just take it out of rotation
+	if err := health.RegisterChecks(quark.GetEnvVar("DISCOVERY"), srv.Info().Name, srv.Info().Address.Host, map[string]api.AgentServiceCheck{
+		"live": {
+			TCP:                            srv.Info().Address.Host,
+			Interval:                       health.ConsulCheckInterval,
+			Timeout:                        health.ConsulCheckTimeout,
+			DeregisterCriticalServiceAfter: health.ConsulDeregisterAfter,
+		},
+		"ready": {
+			GRPC:     srv.Info().Address.Host,
+			Interval: health.ConsulCheckInterval,
+			Timeout:  health.ConsulCheckTimeout,
+		},
+	}); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot register health checks")
+	}
 
-	go func() {
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-		for {
-			msg := broker.Message{
-				Topic: "SampleTopic",
-				Value: "Sample message with timestamp = " + time.Now().String(),
-			}
-
-			srv.Log().InfoWithFields(logger.Fields{
-				"topic": msg.Topic,
-				"value": msg.Value,
-			}, "Sending message")
-
-			if err := srv.Broker().PublishMessage(context.Background(), msg); err != nil {
-				srv.Log().ErrorWithFields(logger.Fields{
-					"error": err,
-				}, "Cannot publish message")
-			}
-
-			// 1 - 5 seconds delay
-			delay := time.Duration(r.Int63n(5) + 1)
-			time.Sleep(delay * time.Second)
-		}
-	}()
+	// dependencies required to serve requests
+	go watchReadiness(health.Checks{
+		"broker":    health.Broker(quark.GetEnvVar("BROKER")),
+		"discovery": health.Discovery(quark.GetEnvVar("DISCOVERY")),
+	})
 
-	done := quark.HandleInterrupt(srv)
 	server := gRPC.NewServer()
 	defer func() {
 		server.Dispose()
@@ -135,5 +161,8 @@ func main() {
 		server.Start(srv)
 	}()
 
-	<-done
+	sig := shutdown.WaitForSignal()
+	srv.Log().InfoWithFields(logger.Fields{"signal": sig}, "Shutting down")
+
+	shutdownGracefully(gracePeriod)
 }
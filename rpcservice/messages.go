@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/gkarlik/quark-go-example/rpcservice/messages"
+	"github.com/gkarlik/quark-go-example/rpcservice/publisher"
+	"github.com/gkarlik/quark-go/logger"
+	"github.com/gkarlik/quark-go/service/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// metadata keys of request ID and authenticated user passed by the gateway
+const (
+	requestIDKey = "x-request-id"
+	userKey      = "x-user"
+)
+
+// key of trace ID propagated by zipkin tracer
+const traceIDKey = "x-b3-traceid"
+
+// time limit of publishing message, including broker confirmation
+const publishTimeout = 10 * time.Second
+
+// Dispose closes broker connection and releases service resources
+func (s *sumService) Dispose() {
+	s.events.Close()
+	s.ServiceBase.Dispose()
+}
+
+// helper function to publish message wrapped in envelope carrying trace context. Message starting
+// new conversation is given empty correlation ID, its own ID is used instead.
+func publishMessage(ctx context.Context, topic string, m messages.Message, traceContext opentracing.TextMapCarrier, correlationID string) error {
+	e, err := messages.Registered.Wrap(m)
+	if err != nil {
+		return err
+	}
+
+	e.CorrelationID = correlationID
+	if e.CorrelationID == "" {
+		e.CorrelationID = e.ID
+	}
+	for k, v := range traceContext {
+		e.Trace[k] = v
+	}
+
+	data, err := e.Marshal()
+	if err != nil {
+		return err
+	}
+	return srv.events.Publish(ctx, topic, publisher.Message{ID: e.ID, ContentType: "application/json", Body: data})
+}
+
+// helper function to publish CalculationPerformed event. Event is published in background, so broker
+// does not delay the response, and events still being published are drained during shutdown.
+func publishCalculation(ctx context.Context, span trace.Span, operation string, operands []int64, result int64, start time.Time) {
+	traceContext := opentracing.TextMapCarrier{}
+	if err := srv.Tracer().InjectSpan(span, opentracing.TextMap, traceContext); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot inject trace context")
+	}
+
+	event := &messages.CalculationPerformed{
+		Operation: operation,
+		Operands:  operands,
+		Result:    result,
+		Latency:   time.Since(start),
+		TraceID:   traceContext[traceIDKey],
+	}
+	// request of the gateway starts the conversation
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md[userKey]; len(v) > 0 {
+			event.User = v[0]
+		}
+		if v := md[requestIDKey]; len(v) > 0 {
+			requestID = v[0]
+		}
+	}
+
+	publishers.Add(1)
+	go func() {
+		defer publishers.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		defer cancel()
+
+		if err := publishMessage(ctx, messages.CalculationsTopic, event, traceContext, requestID); err != nil {
+			srv.Log().ErrorWithFields(logger.Fields{
+				"error": err,
+				"topic": messages.CalculationsTopic,
+			}, "Cannot publish message")
+		}
+	}()
+}
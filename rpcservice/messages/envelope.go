@@ -0,0 +1,71 @@
+package messages
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// encodings of message payload
+const (
+	JSON     = "json"
+	Protobuf = "protobuf"
+)
+
+// Message is implemented by types of messages exchanged through the broker
+type Message interface {
+	// MessageType returns name identifying type of the message
+	MessageType() string
+	// SchemaVersion returns version of the message structure, incremented on incompatible changes
+	SchemaVersion() int
+}
+
+// Envelope wraps message payload with metadata. JSON payload is embedded as it is,
+// protobuf payload as base64 encoded string.
+type Envelope struct {
+	ID            string                     `json:"id"`
+	Type          string                     `json:"type"`
+	SchemaVersion int                        `json:"schema_version"`
+	Timestamp     time.Time                  `json:"timestamp"`
+	CorrelationID string                     `json:"correlation_id,omitempty"`
+	Trace         opentracing.TextMapCarrier `json:"trace,omitempty"`
+	Encoding      string                     `json:"encoding"`
+	Payload       json.RawMessage            `json:"payload"`
+}
+
+// Marshal encodes envelope to be published as broker message value
+func (e *Envelope) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Parse decodes envelope of broker message, payload is decoded by registry
+func Parse(data []byte) (*Envelope, error) {
+	var e Envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("Invalid message envelope: %s", err)
+	}
+	switch {
+	case e.ID == "":
+		return nil, fmt.Errorf("Invalid message envelope: missing id")
+	case e.Type == "":
+		return nil, fmt.Errorf("Invalid message envelope: missing type")
+	case e.SchemaVersion < 1:
+		return nil, fmt.Errorf("Invalid message envelope: missing schema version")
+	case len(e.Payload) == 0:
+		return nil, fmt.Errorf("Invalid message envelope: missing payload")
+	}
+	return &e, nil
+}
+
+// helper function to generate random message ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
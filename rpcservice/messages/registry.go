@@ -0,0 +1,195 @@
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// UnknownTypeError is returned when message type is not registered
+type UnknownTypeError struct {
+	Type string
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("Unknown message type '%s'", e.Type)
+}
+
+// IncompatibleVersionError is returned when schema version of the message is not supported
+type IncompatibleVersionError struct {
+	Type       string
+	Version    int
+	MinVersion int
+	MaxVersion int
+}
+
+func (e *IncompatibleVersionError) Error() string {
+	if e.MinVersion == e.MaxVersion {
+		return fmt.Sprintf("Incompatible schema version %d of message '%s', supported version is %d", e.Version, e.Type, e.MaxVersion)
+	}
+	return fmt.Sprintf("Incompatible schema version %d of message '%s', supported versions are %d-%d", e.Version, e.Type, e.MinVersion, e.MaxVersion)
+}
+
+// registration describes registered message type
+type registration struct {
+	goType     reflect.Type
+	encoding   string
+	minVersion int
+	version    int
+}
+
+// Registry maps message types to Go types. Registry encodes messages into envelopes and decodes
+// envelopes into messages, rejecting schema versions it does not support.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]registration
+}
+
+// NewRegistry creates empty registry
+func NewRegistry() *Registry {
+	return &Registry{types: map[string]registration{}}
+}
+
+// Register adds message type given by pointer to its zero value, e.g. &CalculationPerformed{}. Message of schema versions
+// from minVersion to the current one can be decoded, older messages have to be readable with current type.
+func (r *Registry) Register(m Message, encoding string, minVersion int) error {
+	t := reflect.TypeOf(m)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Message '%s' has to be a pointer to struct", m.MessageType())
+	}
+	switch encoding {
+	case JSON:
+	case Protobuf:
+		if _, ok := m.(proto.Message); !ok {
+			return fmt.Errorf("Message '%s' is not a protocol buffers message", m.MessageType())
+		}
+	default:
+		return fmt.Errorf("Unknown encoding '%s'", encoding)
+	}
+	if minVersion < 1 || minVersion > m.SchemaVersion() {
+		return fmt.Errorf("Incorrect minimal schema version %d of message '%s'", minVersion, m.MessageType())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.types[m.MessageType()]; ok {
+		return fmt.Errorf("Message '%s' is already registered", m.MessageType())
+	}
+	r.types[m.MessageType()] = registration{
+		goType:     t.Elem(),
+		encoding:   encoding,
+		minVersion: minVersion,
+		version:    m.SchemaVersion(),
+	}
+	return nil
+}
+
+// MustRegister adds message type like Register and panics on error
+func (r *Registry) MustRegister(m Message, encoding string, minVersion int) {
+	if err := r.Register(m, encoding, minVersion); err != nil {
+		panic(err)
+	}
+}
+
+// helper function to find registration of message type
+func (r *Registry) lookup(messageType string) (registration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reg, ok := r.types[messageType]
+	if !ok {
+		return registration{}, &UnknownTypeError{Type: messageType}
+	}
+	return reg, nil
+}
+
+// Wrap encodes registered message into new envelope, correlation ID and trace context
+// are set by the publisher
+func (r *Registry) Wrap(m Message) (*Envelope, error) {
+	reg, err := r.lookup(m.MessageType())
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	switch reg.encoding {
+	case Protobuf:
+		var data []byte
+		if data, err = proto.Marshal(m.(proto.Message)); err == nil {
+			payload, err = json.Marshal(data)
+		}
+	default:
+		payload, err = json.Marshal(m)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		ID:            id,
+		Type:          m.MessageType(),
+		SchemaVersion: reg.version,
+		Timestamp:     time.Now().UTC(),
+		Trace:         opentracing.TextMapCarrier{},
+		Encoding:      reg.encoding,
+		Payload:       payload,
+	}, nil
+}
+
+// Open decodes message of the envelope into its registered type
+func (r *Registry) Open(e *Envelope) (Message, error) {
+	reg, err := r.lookup(e.Type)
+	if err != nil {
+		return nil, err
+	}
+	if e.SchemaVersion < reg.minVersion || e.SchemaVersion > reg.version {
+		return nil, &IncompatibleVersionError{
+			Type:       e.Type,
+			Version:    e.SchemaVersion,
+			MinVersion: reg.minVersion,
+			MaxVersion: reg.version,
+		}
+	}
+	if e.Encoding != reg.encoding {
+		return nil, fmt.Errorf("Message '%s' has to be encoded as %s, not %s", e.Type, reg.encoding, e.Encoding)
+	}
+
+	m := reflect.New(reg.goType).Interface().(Message)
+	switch reg.encoding {
+	case Protobuf:
+		var data []byte
+		if err = json.Unmarshal(e.Payload, &data); err == nil {
+			err = proto.Unmarshal(data, m.(proto.Message))
+		}
+	default:
+		err = json.Unmarshal(e.Payload, m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid payload of message '%s': %s", e.Type, err)
+	}
+	return m, nil
+}
+
+// Unmarshal decodes broker message value into envelope and its message
+func (r *Registry) Unmarshal(data []byte) (*Envelope, Message, error) {
+	e, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := r.Open(e)
+	if err != nil {
+		return e, nil, err
+	}
+	return e, m, nil
+}
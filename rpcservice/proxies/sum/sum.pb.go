@@ -11,6 +11,15 @@ It is generated from these files:
 It has these top-level messages:
 	SumRequest
 	SumResponse
+	OperationRequest
+	NegateRequest
+	OperationResponse
+	DivideResponse
+	DecimalRequest
+	DecimalResponse
+	BatchSumRequest
+	BatchSumResponse
+	NumberRequest
 */
 package sum
 
@@ -74,9 +83,234 @@ func (m *SumResponse) GetSum() int64 {
 	return 0
 }
 
+type OperationRequest struct {
+	A int64 `protobuf:"varint,1,opt,name=A,json=a" json:"A,omitempty"`
+	B int64 `protobuf:"varint,2,opt,name=B,json=b" json:"B,omitempty"`
+}
+
+func (m *OperationRequest) Reset()                    { *m = OperationRequest{} }
+func (m *OperationRequest) String() string            { return proto.CompactTextString(m) }
+func (*OperationRequest) ProtoMessage()               {}
+func (*OperationRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *OperationRequest) GetA() int64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *OperationRequest) GetB() int64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type NegateRequest struct {
+	A int64 `protobuf:"varint,1,opt,name=A,json=a" json:"A,omitempty"`
+}
+
+func (m *NegateRequest) Reset()                    { *m = NegateRequest{} }
+func (m *NegateRequest) String() string            { return proto.CompactTextString(m) }
+func (*NegateRequest) ProtoMessage()               {}
+func (*NegateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *NegateRequest) GetA() int64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+type OperationResponse struct {
+	Result int64 `protobuf:"varint,1,opt,name=Result,json=result" json:"Result,omitempty"`
+}
+
+func (m *OperationResponse) Reset()                    { *m = OperationResponse{} }
+func (m *OperationResponse) String() string            { return proto.CompactTextString(m) }
+func (*OperationResponse) ProtoMessage()               {}
+func (*OperationResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *OperationResponse) GetResult() int64 {
+	if m != nil {
+		return m.Result
+	}
+	return 0
+}
+
+type DivideResponse struct {
+	Result float64 `protobuf:"fixed64,1,opt,name=Result,json=result" json:"Result,omitempty"`
+}
+
+func (m *DivideResponse) Reset()                    { *m = DivideResponse{} }
+func (m *DivideResponse) String() string            { return proto.CompactTextString(m) }
+func (*DivideResponse) ProtoMessage()               {}
+func (*DivideResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *DivideResponse) GetResult() float64 {
+	if m != nil {
+		return m.Result
+	}
+	return 0
+}
+
+type DecimalRequest struct {
+	Operation string `protobuf:"bytes,1,opt,name=Operation,json=operation" json:"Operation,omitempty"`
+	A         string `protobuf:"bytes,2,opt,name=A,json=a" json:"A,omitempty"`
+	B         string `protobuf:"bytes,3,opt,name=B,json=b" json:"B,omitempty"`
+	Precision int32  `protobuf:"varint,4,opt,name=Precision,json=precision" json:"Precision,omitempty"`
+}
+
+func (m *DecimalRequest) Reset()                    { *m = DecimalRequest{} }
+func (m *DecimalRequest) String() string            { return proto.CompactTextString(m) }
+func (*DecimalRequest) ProtoMessage()               {}
+func (*DecimalRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *DecimalRequest) GetOperation() string {
+	if m != nil {
+		return m.Operation
+	}
+	return ""
+}
+
+func (m *DecimalRequest) GetA() string {
+	if m != nil {
+		return m.A
+	}
+	return ""
+}
+
+func (m *DecimalRequest) GetB() string {
+	if m != nil {
+		return m.B
+	}
+	return ""
+}
+
+func (m *DecimalRequest) GetPrecision() int32 {
+	if m != nil {
+		return m.Precision
+	}
+	return 0
+}
+
+type DecimalResponse struct {
+	Result string `protobuf:"bytes,1,opt,name=Result,json=result" json:"Result,omitempty"`
+}
+
+func (m *DecimalResponse) Reset()                    { *m = DecimalResponse{} }
+func (m *DecimalResponse) String() string            { return proto.CompactTextString(m) }
+func (*DecimalResponse) ProtoMessage()               {}
+func (*DecimalResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *DecimalResponse) GetResult() string {
+	if m != nil {
+		return m.Result
+	}
+	return ""
+}
+
+type BatchSumRequest struct {
+	Index int32 `protobuf:"varint,1,opt,name=Index,json=index" json:"Index,omitempty"`
+	A     int64 `protobuf:"varint,2,opt,name=A,json=a" json:"A,omitempty"`
+	B     int64 `protobuf:"varint,3,opt,name=B,json=b" json:"B,omitempty"`
+}
+
+func (m *BatchSumRequest) Reset()                    { *m = BatchSumRequest{} }
+func (m *BatchSumRequest) String() string            { return proto.CompactTextString(m) }
+func (*BatchSumRequest) ProtoMessage()               {}
+func (*BatchSumRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *BatchSumRequest) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *BatchSumRequest) GetA() int64 {
+	if m != nil {
+		return m.A
+	}
+	return 0
+}
+
+func (m *BatchSumRequest) GetB() int64 {
+	if m != nil {
+		return m.B
+	}
+	return 0
+}
+
+type BatchSumResponse struct {
+	Index int32  `protobuf:"varint,1,opt,name=Index,json=index" json:"Index,omitempty"`
+	Sum   int64  `protobuf:"varint,2,opt,name=Sum,json=sum" json:"Sum,omitempty"`
+	Code  int32  `protobuf:"varint,3,opt,name=Code,json=code" json:"Code,omitempty"`
+	Error string `protobuf:"bytes,4,opt,name=Error,json=error" json:"Error,omitempty"`
+}
+
+func (m *BatchSumResponse) Reset()                    { *m = BatchSumResponse{} }
+func (m *BatchSumResponse) String() string            { return proto.CompactTextString(m) }
+func (*BatchSumResponse) ProtoMessage()               {}
+func (*BatchSumResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *BatchSumResponse) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *BatchSumResponse) GetSum() int64 {
+	if m != nil {
+		return m.Sum
+	}
+	return 0
+}
+
+func (m *BatchSumResponse) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *BatchSumResponse) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type NumberRequest struct {
+	Value int64 `protobuf:"varint,1,opt,name=Value,json=value" json:"Value,omitempty"`
+}
+
+func (m *NumberRequest) Reset()                    { *m = NumberRequest{} }
+func (m *NumberRequest) String() string            { return proto.CompactTextString(m) }
+func (*NumberRequest) ProtoMessage()               {}
+func (*NumberRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *NumberRequest) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*SumRequest)(nil), "SumRequest")
 	proto.RegisterType((*SumResponse)(nil), "SumResponse")
+	proto.RegisterType((*OperationRequest)(nil), "OperationRequest")
+	proto.RegisterType((*NegateRequest)(nil), "NegateRequest")
+	proto.RegisterType((*OperationResponse)(nil), "OperationResponse")
+	proto.RegisterType((*DivideResponse)(nil), "DivideResponse")
+	proto.RegisterType((*DecimalRequest)(nil), "DecimalRequest")
+	proto.RegisterType((*DecimalResponse)(nil), "DecimalResponse")
+	proto.RegisterType((*BatchSumRequest)(nil), "BatchSumRequest")
+	proto.RegisterType((*BatchSumResponse)(nil), "BatchSumResponse")
+	proto.RegisterType((*NumberRequest)(nil), "NumberRequest")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -91,6 +325,15 @@ const _ = grpc.SupportPackageIsVersion4
 
 type SumServiceClient interface {
 	Sum(ctx context.Context, in *SumRequest, opts ...grpc.CallOption) (*SumResponse, error)
+	Subtract(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Divide(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*DivideResponse, error)
+	Power(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Modulo(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Negate(ctx context.Context, in *NegateRequest, opts ...grpc.CallOption) (*OperationResponse, error)
+	Calculate(ctx context.Context, in *DecimalRequest, opts ...grpc.CallOption) (*DecimalResponse, error)
+	BatchSum(ctx context.Context, opts ...grpc.CallOption) (SumService_BatchSumClient, error)
+	TotalSum(ctx context.Context, opts ...grpc.CallOption) (SumService_TotalSumClient, error)
+	RunningSum(ctx context.Context, opts ...grpc.CallOption) (SumService_RunningSumClient, error)
 }
 
 type sumServiceClient struct {
@@ -110,10 +353,169 @@ func (c *sumServiceClient) Sum(ctx context.Context, in *SumRequest, opts ...grpc
 	return out, nil
 }
 
+func (c *sumServiceClient) Subtract(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := grpc.Invoke(ctx, "/SumService/Subtract", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sumServiceClient) Divide(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*DivideResponse, error) {
+	out := new(DivideResponse)
+	err := grpc.Invoke(ctx, "/SumService/Divide", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sumServiceClient) Power(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := grpc.Invoke(ctx, "/SumService/Power", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sumServiceClient) Modulo(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := grpc.Invoke(ctx, "/SumService/Modulo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sumServiceClient) Negate(ctx context.Context, in *NegateRequest, opts ...grpc.CallOption) (*OperationResponse, error) {
+	out := new(OperationResponse)
+	err := grpc.Invoke(ctx, "/SumService/Negate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sumServiceClient) Calculate(ctx context.Context, in *DecimalRequest, opts ...grpc.CallOption) (*DecimalResponse, error) {
+	out := new(DecimalResponse)
+	err := grpc.Invoke(ctx, "/SumService/Calculate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sumServiceClient) BatchSum(ctx context.Context, opts ...grpc.CallOption) (SumService_BatchSumClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_SumService_serviceDesc.Streams[0], c.cc, "/SumService/BatchSum", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sumServiceBatchSumClient{stream}
+	return x, nil
+}
+
+type SumService_BatchSumClient interface {
+	Send(*BatchSumRequest) error
+	Recv() (*BatchSumResponse, error)
+	grpc.ClientStream
+}
+
+type sumServiceBatchSumClient struct {
+	grpc.ClientStream
+}
+
+func (x *sumServiceBatchSumClient) Send(m *BatchSumRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sumServiceBatchSumClient) Recv() (*BatchSumResponse, error) {
+	m := new(BatchSumResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sumServiceClient) TotalSum(ctx context.Context, opts ...grpc.CallOption) (SumService_TotalSumClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_SumService_serviceDesc.Streams[1], c.cc, "/SumService/TotalSum", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sumServiceTotalSumClient{stream}
+	return x, nil
+}
+
+type SumService_TotalSumClient interface {
+	Send(*NumberRequest) error
+	CloseAndRecv() (*SumResponse, error)
+	grpc.ClientStream
+}
+
+type sumServiceTotalSumClient struct {
+	grpc.ClientStream
+}
+
+func (x *sumServiceTotalSumClient) Send(m *NumberRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sumServiceTotalSumClient) CloseAndRecv() (*SumResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(SumResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sumServiceClient) RunningSum(ctx context.Context, opts ...grpc.CallOption) (SumService_RunningSumClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_SumService_serviceDesc.Streams[2], c.cc, "/SumService/RunningSum", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sumServiceRunningSumClient{stream}
+	return x, nil
+}
+
+type SumService_RunningSumClient interface {
+	Send(*NumberRequest) error
+	Recv() (*SumResponse, error)
+	grpc.ClientStream
+}
+
+type sumServiceRunningSumClient struct {
+	grpc.ClientStream
+}
+
+func (x *sumServiceRunningSumClient) Send(m *NumberRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *sumServiceRunningSumClient) Recv() (*SumResponse, error) {
+	m := new(SumResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for SumService service
 
 type SumServiceServer interface {
 	Sum(context.Context, *SumRequest) (*SumResponse, error)
+	Subtract(context.Context, *OperationRequest) (*OperationResponse, error)
+	Divide(context.Context, *OperationRequest) (*DivideResponse, error)
+	Power(context.Context, *OperationRequest) (*OperationResponse, error)
+	Modulo(context.Context, *OperationRequest) (*OperationResponse, error)
+	Negate(context.Context, *NegateRequest) (*OperationResponse, error)
+	Calculate(context.Context, *DecimalRequest) (*DecimalResponse, error)
+	BatchSum(SumService_BatchSumServer) error
+	TotalSum(SumService_TotalSumServer) error
+	RunningSum(SumService_RunningSumServer) error
 }
 
 func RegisterSumServiceServer(s *grpc.Server, srv SumServiceServer) {
@@ -138,6 +540,192 @@ func _SumService_Sum_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SumService_Subtract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SumServiceServer).Subtract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/SumService/Subtract",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SumServiceServer).Subtract(ctx, req.(*OperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SumService_Divide_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SumServiceServer).Divide(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/SumService/Divide",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SumServiceServer).Divide(ctx, req.(*OperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SumService_Power_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SumServiceServer).Power(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/SumService/Power",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SumServiceServer).Power(ctx, req.(*OperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SumService_Modulo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SumServiceServer).Modulo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/SumService/Modulo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SumServiceServer).Modulo(ctx, req.(*OperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SumService_Negate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NegateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SumServiceServer).Negate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/SumService/Negate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SumServiceServer).Negate(ctx, req.(*NegateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SumService_Calculate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecimalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SumServiceServer).Calculate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/SumService/Calculate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SumServiceServer).Calculate(ctx, req.(*DecimalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SumService_BatchSum_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SumServiceServer).BatchSum(&sumServiceBatchSumServer{stream})
+}
+
+type SumService_BatchSumServer interface {
+	Send(*BatchSumResponse) error
+	Recv() (*BatchSumRequest, error)
+	grpc.ServerStream
+}
+
+type sumServiceBatchSumServer struct {
+	grpc.ServerStream
+}
+
+func (x *sumServiceBatchSumServer) Send(m *BatchSumResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sumServiceBatchSumServer) Recv() (*BatchSumRequest, error) {
+	m := new(BatchSumRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _SumService_TotalSum_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SumServiceServer).TotalSum(&sumServiceTotalSumServer{stream})
+}
+
+type SumService_TotalSumServer interface {
+	SendAndClose(*SumResponse) error
+	Recv() (*NumberRequest, error)
+	grpc.ServerStream
+}
+
+type sumServiceTotalSumServer struct {
+	grpc.ServerStream
+}
+
+func (x *sumServiceTotalSumServer) SendAndClose(m *SumResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sumServiceTotalSumServer) Recv() (*NumberRequest, error) {
+	m := new(NumberRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _SumService_RunningSum_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SumServiceServer).RunningSum(&sumServiceRunningSumServer{stream})
+}
+
+type SumService_RunningSumServer interface {
+	Send(*SumResponse) error
+	Recv() (*NumberRequest, error)
+	grpc.ServerStream
+}
+
+type sumServiceRunningSumServer struct {
+	grpc.ServerStream
+}
+
+func (x *sumServiceRunningSumServer) Send(m *SumResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *sumServiceRunningSumServer) Recv() (*NumberRequest, error) {
+	m := new(NumberRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _SumService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "SumService",
 	HandlerType: (*SumServiceServer)(nil),
@@ -146,22 +734,85 @@ var _SumService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Sum",
 			Handler:    _SumService_Sum_Handler,
 		},
+		{
+			MethodName: "Subtract",
+			Handler:    _SumService_Subtract_Handler,
+		},
+		{
+			MethodName: "Divide",
+			Handler:    _SumService_Divide_Handler,
+		},
+		{
+			MethodName: "Power",
+			Handler:    _SumService_Power_Handler,
+		},
+		{
+			MethodName: "Modulo",
+			Handler:    _SumService_Modulo_Handler,
+		},
+		{
+			MethodName: "Negate",
+			Handler:    _SumService_Negate_Handler,
+		},
+		{
+			MethodName: "Calculate",
+			Handler:    _SumService_Calculate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchSum",
+			Handler:       _SumService_BatchSum_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "TotalSum",
+			Handler:       _SumService_TotalSum_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "RunningSum",
+			Handler:       _SumService_RunningSum_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "sum/sum.proto",
 }
 
 func init() { proto.RegisterFile("sum/sum.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 132 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x2d, 0x2e, 0xcd, 0xd5,
-	0x2f, 0x2e, 0xcd, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0xd2, 0xe0, 0xe2, 0x0a, 0x2e, 0xcd,
-	0x0d, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e, 0x11, 0xe2, 0xe1, 0x62, 0x74, 0x94, 0x60, 0x54, 0x60,
-	0xd4, 0x60, 0x0e, 0x62, 0x4c, 0x04, 0xf1, 0x9c, 0x24, 0x98, 0x20, 0xbc, 0x24, 0x25, 0x79, 0x2e,
-	0x6e, 0xb0, 0xca, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54, 0x21, 0x01, 0x2e, 0xe6, 0xe0, 0xd2, 0x5c,
-	0xa8, 0x34, 0x73, 0x71, 0x69, 0xae, 0x91, 0x01, 0xd8, 0xa8, 0xe0, 0xd4, 0xa2, 0xb2, 0xcc, 0xe4,
-	0x54, 0x21, 0x25, 0xb0, 0xbc, 0x10, 0xb7, 0x1e, 0xc2, 0x78, 0x29, 0x1e, 0x3d, 0x24, 0x13, 0x94,
-	0x18, 0x92, 0xd8, 0xc0, 0x6e, 0x30, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x50, 0x5f, 0x84, 0x3d,
-	0x94, 0x00, 0x00, 0x00,
+	// 478 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x94, 0x54, 0x5d, 0x8b, 0xd3, 0x40,
+	0x14, 0xcd, 0x6c, 0x9a, 0xd0, 0xdc, 0xdd, 0xb6, 0xe9, 0x20, 0xb2, 0x14, 0xc5, 0x65, 0x40, 0x88,
+	0x28, 0x63, 0x74, 0xf1, 0x07, 0xb8, 0x5d, 0x1f, 0x7c, 0x70, 0x5d, 0x52, 0xf1, 0x3d, 0x1f, 0x97,
+	0xdd, 0x40, 0x92, 0xa9, 0x93, 0x4c, 0xf5, 0x7f, 0xfb, 0x07, 0x24, 0x93, 0xa4, 0x6d, 0xb2, 0x2d,
+	0xd4, 0xc7, 0x33, 0x3d, 0xf7, 0x9e, 0x73, 0x7b, 0x0e, 0x81, 0x49, 0xa9, 0xf2, 0xf7, 0xa5, 0xca,
+	0xf9, 0x5a, 0x8a, 0x4a, 0x30, 0x0f, 0x60, 0xa5, 0xf2, 0x00, 0x7f, 0x29, 0x2c, 0x2b, 0x7a, 0x01,
+	0xe4, 0xf3, 0x25, 0xb9, 0x22, 0x9e, 0x19, 0x90, 0xb0, 0x46, 0x37, 0x97, 0x67, 0x0d, 0x8a, 0xd8,
+	0x2b, 0x38, 0xd7, 0xcc, 0x72, 0x2d, 0x8a, 0x12, 0xa9, 0x0b, 0xe6, 0x4a, 0xe5, 0xed, 0xcf, 0x66,
+	0xa9, 0x72, 0xc6, 0xc1, 0xfd, 0xbe, 0x46, 0x19, 0x56, 0xa9, 0x28, 0x4e, 0x59, 0xf8, 0x12, 0x26,
+	0x77, 0xf8, 0x10, 0x56, 0x78, 0x90, 0xcc, 0xde, 0xc2, 0x7c, 0x6f, 0x5d, 0xab, 0xfa, 0x1c, 0xec,
+	0x00, 0x4b, 0x95, 0x55, 0x2d, 0xcf, 0x96, 0x1a, 0x31, 0x0f, 0xa6, 0xb7, 0xe9, 0x26, 0x4d, 0xf0,
+	0x08, 0x93, 0x6c, 0x99, 0x8f, 0x30, 0xbd, 0xc5, 0x38, 0xcd, 0xc3, 0xac, 0x93, 0x7d, 0x01, 0xce,
+	0x56, 0x48, 0x93, 0x9d, 0xc0, 0x11, 0xdd, 0x43, 0x63, 0xea, 0x4c, 0xbf, 0x76, 0x17, 0x98, 0x0d,
+	0x8a, 0xea, 0xc9, 0x7b, 0x89, 0x71, 0x5a, 0xd6, 0x93, 0xa3, 0x2b, 0xe2, 0x59, 0x81, 0xb3, 0xee,
+	0x1e, 0xd8, 0x1b, 0x98, 0x6d, 0x95, 0x0e, 0x9a, 0x72, 0xb6, 0xa6, 0x96, 0x30, 0xbb, 0x09, 0xab,
+	0xf8, 0x71, 0x2f, 0x8a, 0x67, 0x60, 0x7d, 0x2d, 0x12, 0xfc, 0xa3, 0x99, 0x56, 0x60, 0xa5, 0x35,
+	0xd8, 0xb9, 0x31, 0x7b, 0x6e, 0xf4, 0xff, 0x99, 0x80, 0xbb, 0x5b, 0xd2, 0x0a, 0x1e, 0xde, 0xf2,
+	0x24, 0x3b, 0x4a, 0x61, 0xb4, 0x14, 0x09, 0xea, 0x65, 0x56, 0x30, 0x8a, 0x45, 0xa2, 0x67, 0xbf,
+	0x48, 0x29, 0xa4, 0xbe, 0xcc, 0x09, 0x2c, 0xac, 0x01, 0x7b, 0x0d, 0x93, 0x3b, 0x95, 0x47, 0x28,
+	0xf7, 0x8c, 0xfe, 0x0c, 0x33, 0x85, 0x6d, 0x22, 0xd6, 0xa6, 0x06, 0x1f, 0xff, 0x9a, 0xba, 0x58,
+	0x2b, 0x94, 0x9b, 0x34, 0x46, 0xca, 0xb4, 0x22, 0x3d, 0xe7, 0xbb, 0x0b, 0x17, 0x17, 0x7c, 0xcf,
+	0x29, 0x33, 0xe8, 0x35, 0x8c, 0x57, 0x2a, 0xaa, 0x64, 0x18, 0x57, 0x74, 0xce, 0x87, 0x55, 0x5a,
+	0x50, 0xfe, 0xa4, 0x0e, 0xcc, 0xa0, 0x1c, 0xec, 0x26, 0xf8, 0x43, 0x23, 0x33, 0xde, 0x2f, 0x05,
+	0x33, 0xa8, 0x0f, 0xd6, 0xbd, 0xf8, 0x8d, 0xf2, 0x74, 0x85, 0x0f, 0x60, 0x7f, 0x13, 0x89, 0xca,
+	0xc4, 0x7f, 0x99, 0x6a, 0x9a, 0x4d, 0xa7, 0xbc, 0x57, 0xf1, 0x23, 0x7c, 0x1f, 0x9c, 0x65, 0x98,
+	0xc5, 0x2a, 0xab, 0x47, 0x66, 0xbc, 0xdf, 0xcf, 0x85, 0xcb, 0x07, 0x35, 0x62, 0x06, 0xfd, 0x04,
+	0xe3, 0x2e, 0x6b, 0xea, 0xf2, 0x41, 0x77, 0x16, 0x73, 0x3e, 0x2c, 0x02, 0x33, 0x3c, 0xe2, 0x13,
+	0xfa, 0x0e, 0xc6, 0x3f, 0x44, 0x15, 0x66, 0xf5, 0xd8, 0x94, 0xf7, 0x72, 0x1c, 0xc6, 0xe1, 0x11,
+	0xea, 0x03, 0x04, 0xaa, 0x28, 0xd2, 0xe2, 0xe1, 0x24, 0xbe, 0x4f, 0x22, 0x5b, 0x7f, 0x54, 0xae,
+	0xff, 0x05, 0x00, 0x00, 0xff, 0xff, 0x56, 0x85, 0x79, 0xe5, 0x65, 0x04, 0x00, 0x00,
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gkarlik/quark-go-example/rpcservice/shutdown"
+	"github.com/gkarlik/quark-go/logger"
+	sd "github.com/gkarlik/quark-go/service/discovery"
+	"google.golang.org/grpc"
+)
+
+// messages being published in background, drained during shutdown
+var publishers sync.WaitGroup
+
+// gRPC server created by quark, stored when service instance is registered
+var grpcServer atomic.Value
+
+// helper function to stop gRPC server gracefully, in-flight calls and streams still running
+// when context is done are cancelled
+func stopServer(ctx context.Context) error {
+	server, ok := grpcServer.Load().(*grpc.Server)
+	if !ok {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+// function to shut down service gracefully. Service is removed from service discovery first, so no new
+// calls are routed to it, then in-flight calls and messages are given grace period to finish.
+func shutdownGracefully(gracePeriod time.Duration) {
+	if err := srv.Discovery().DeregisterService(sd.WithInfo(srv.Info())); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Cannot deregister service")
+	}
+	// health service reports NOT_SERVING from now on
+	healthServer.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	if err := stopServer(ctx); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Calls not drained within grace period")
+		return
+	}
+	if err := shutdown.Wait(ctx, &publishers); err != nil {
+		srv.Log().ErrorWithFields(logger.Fields{"error": err}, "Messages not drained within grace period")
+		return
+	}
+	srv.Log().Info("Calls and messages drained")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/gkarlik/quark-go"
+	proxy "github.com/gkarlik/quark-go-example/rpcservice/proxies/sum"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// limit of numbers summed by single TotalSum stream, operands are kept until the total is published
+const maxTotalSumOperands = 10000
+
+// function to handle sum of numbers streamed by the client, total is returned when client
+// closes the stream and published as single calculation. Streams exceeding maxTotalSumOperands
+// numbers are rejected.
+func (s *sumService) TotalSum(stream proxy.SumService_TotalSumServer) error {
+	start := time.Now()
+	span := quark.StartRPCSpan(stream.Context(), srv, "total_sum_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing total sum function")
+
+	var (
+		total    int64
+		operands []int64
+	)
+	for {
+		r, err := stream.Recv()
+		if err == io.EOF {
+			if err := stream.SendAndClose(&proxy.SumResponse{Sum: total}); err != nil {
+				return err
+			}
+			publishCalculation(stream.Context(), span, "total_sum", operands, total, start)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(operands) == maxTotalSumOperands {
+			return grpc.Errorf(codes.OutOfRange, "TotalSum accepts at most %d numbers", maxTotalSumOperands)
+		}
+
+		var ok bool
+		if total, ok = addInt64(total, r.Value); !ok {
+			return overflow("TotalSum")
+		}
+		operands = append(operands, r.Value)
+	}
+}
+
+// function to handle running sum of numbers streamed by the client - sum of numbers received
+// so far is sent back after every number
+func (s *sumService) RunningSum(stream proxy.SumService_RunningSumServer) error {
+	span := quark.StartRPCSpan(stream.Context(), srv, "running_sum_handler")
+	defer span.Finish()
+
+	srv.Log().Info("Executing running sum function")
+
+	var total int64
+	for {
+		r, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// every number is a calculation of its own, adding it to the sum so far
+		start := time.Now()
+		previous := total
+
+		var ok bool
+		if total, ok = addInt64(total, r.Value); !ok {
+			return overflow("RunningSum")
+		}
+		if err := stream.Send(&proxy.SumResponse{Sum: total}); err != nil {
+			return err
+		}
+		publishCalculation(stream.Context(), span, "running_sum", []int64{previous, r.Value}, total, start)
+	}
+}